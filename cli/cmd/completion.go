@@ -0,0 +1,29 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion",
+	Short: "Print the bash completion script for tridentctl",
+	Long: `Print the bash completion script for tridentctl, which completes commands, flags and the
+names of the nodes running Trident.  Load it with 'source <(tridentctl completion)'.  In zsh,
+first run 'autoload -U +X bashcompinit && bashcompinit'.`,
+	Args: cobra.NoArgs,
+	// Printing the script needs no access to Trident
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RootCmd.GenBashCompletion(os.Stdout)
+	},
+}
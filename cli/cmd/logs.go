@@ -3,49 +3,379 @@
 package cmd
 
 import (
-	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/netapp/trident/config"
+	"github.com/dustin/go-humanize"
+	"github.com/ghodss/yaml"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+	k8s "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/netapp/trident/cli/api"
+	"github.com/netapp/trident/config"
+	frontendcsi "github.com/netapp/trident/frontend/csi"
+	"github.com/netapp/trident/frontend/rest"
+	tridentv1 "github.com/netapp/trident/persistent_store/crd/apis/netapp/v1"
+	"github.com/netapp/trident/utils"
 )
 
 const (
-	logNameTrident         = "trident-controller"
-	logNameTridentPrevious = "trident-controller-previous"
-	logNameNode            = "trident-node"
-	logNameNodePrevious    = "trident-node-previous"
+	logNameTrident          = "trident-controller"
+	logNameTridentPrevious  = "trident-controller-previous"
+	logNameNode             = "trident-node"
+	logNameNodePrevious     = "trident-node-previous"
+	logNameOperator         = "trident-operator"
+	logNameOperatorPrevious = "trident-operator-previous"
+
+	operatorContainerName = "trident-operator"
+
+	logNameControllerArgs = "controller-args.txt"
+	logNameStorageClasses = "storageclasses.txt"
+	logNameRedaction      = "redaction-report.txt"
+	logNameTridentConfig  = "trident-config.yaml"
+	logNameConfigDiff     = "config-diff.txt"
+	logNameTLSInfo        = "tls-info.txt"
+	logNameEvents         = "events"
+	logNameVersion        = "version"
+
+	levelLogErrors   = "errors.log"
+	levelLogWarnings = "warnings.log"
+	levelLogInfo     = "info.log"
+	levelLogOther    = "other.log"
+
+	logNameAPIAccess         = "api-access.txt"
+	logNameAPIAccessPrevious = "api-access-previous.txt"
+
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
 
 	logTypeAuto    = "auto"
 	logTypeTrident = "trident"
 	logTypeAll     = "all"
 
-	archiveFilenameFormat = "support-2006-01-02T15-04-05-MST.zip"
+	runtimeDocker = "docker"
+	runtimePodman = "podman"
+
+	archiveFilenameFormat = "support-2006-01-02T15-04-05-MST"
+	archiveManifestName   = "manifest.json"
+
+	defaultCompressThreshold = 4096
+
+	restCallCompleteMessage = "REST API call complete."
 )
 
 var (
-	logType     string
-	archive     bool
-	previous    bool
-	node        string
-	sidecars    bool
-	zipFileName string
-	zipWriter   *zip.Writer
-	logErrors   []byte
+	logType       string
+	archive       bool
+	previous      bool
+	noFallback    bool
+	logsVerbose   bool
+	logsQuiet     bool
+	nodes         []string
+	nodeSelector  string
+	nodePattern   string
+	excludedNodes []string
+	skippedNodes  []string
+	logsContainer string
+	sidecars      bool
+	zipFileName   string
+	archiveWriter supportArchiveWriter
+	archiveFormat string
+	outputDir     string
+	logsOutDir    string
+	archiveName   string
+	forceArchive  bool
+	logErrors     logErrorList
+
+	logsConfigFile string
+	apiAccess      bool
+	apiErrorsOnly  bool
+	nodesOnly      bool
+	nodeOnly       bool
+	controllerOnly bool
+
+	compressThreshold int
+	compressionLevel  int
+	archiveManifest   []archiveManifestEntry
+
+	splitSize        string
+	archiveSplitSize int64
+	// The files of the support archive, if split into volumes
+	archiveVolumes []string
+
+	containerRuntime string
+	runtimeContainer string
+
+	teeFileName   string
+	colorMode     string
+	colorize      bool
+	pager         bool
+	consoleOutput io.Writer = os.Stdout
+
+	tailLines int64
+	since     string
+	sinceTime string
+
+	// The size beyond which each container log is truncated, if positive
+	maxLogBytes int64
+
+	// The number of times to retry a transient failure to get a container log
+	logsRetries  int
+	retryBackoff = time.Second
+
+	// The time after which a Kubernetes CLI command is killed, if positive
+	requestTimeout time.Duration
+	// Every Kubernetes request fails once this context is canceled, such as when an archive
+	// collection is interrupted
+	collectionContext = context.Background()
+
+	timestamps bool
+
+	follow bool
+	// Followed log streams stop when this context is canceled
+	followContext = context.Background()
+	followWait    sync.WaitGroup
+
+	parallelism int
+	// Guards the collected logs, errors, and results while logs are collected concurrently
+	logsLock sync.Mutex
+
+	aroundTime   string
+	aroundWindow time.Duration
+
+	fromEvents bool
+
+	ownedBy string
+
+	estimate     bool
+	logEstimates []logEstimate
+
+	masks        []string
+	maskReplacer *strings.Replacer
+
+	startupArgs bool
+
+	splitByLevel bool
+	levelsOnly   bool
+	levelLogs    = make(map[string]*bytes.Buffer)
+
+	logsPVName string
+
+	junitFileName     string
+	collectionResults []collectionResult
+
+	storageClassDetail bool
+
+	tlsInfo bool
+
+	describePods  bool
+	collectEvents bool
+	operatorLogs  bool
+	tridentCRs    bool
+	// Whether --describe, --events, --operator and --crs were specified, so archive auto mode does
+	// not override them
+	describePodsSet  bool
+	collectEventsSet bool
+	operatorLogsSet  bool
+	tridentCRsSet    bool
+	previousSet      bool
+	sidecarsSet      bool
+	noPrevious       bool
+
+	groupByArray bool
+	// Container logs retained for grouping by storage array
+	arrayLogSources = make(map[string][]byte)
+
+	tridentConfig      bool
+	configDiffBaseline string
+
+	autoExpand    bool
+	autoExpandMax int
+	controllerLog []byte
+
+	goroutineID    int
+	goroutineFound bool
+
+	decodeBase64 bool
+
+	logsRate        float64
+	logsRateLimiter flowcontrol.RateLimiter
+
+	notifyURL string
+	caseID    string
+
+	redact          bool
+	redactionReport bool
+	// Counts of redacted matches per pattern and log
+	redactionCounts = make(map[string]map[string]int)
+
+	// Collected logs are limited to lines containing any of these strings, if set
+	lineFilters []string
+
+	grepPattern string
+	invertMatch bool
+	grepRegex   *regexp.Regexp
+
+	// The time range to which collected logs are limited, if set
+	logSinceTime time.Time
+	logUntilTime time.Time
 )
 
+// collectionResult records the outcome of collecting one container log.
+type collectionResult struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	Node      string `json:"node,omitempty"`
+	Previous  bool   `json:"previous"`
+	Bytes     int    `json:"bytes"`
+	Error     string `json:"error,omitempty"`
+}
+
+// logEstimate records the size of a log that would have been collected, and how it was estimated.
+type logEstimate struct {
+	Name  string
+	Lines int
+	Bytes int
+	Basis string
+}
+
+// archiveManifestEntry describes one entry written to the support archive.  The SHA-256 digest
+// of its content lets a recipient verify that the archive was not corrupted in transit.
+type archiveManifestEntry struct {
+	Name        string `json:"name"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+	Compression string `json:"compression"`
+	// The volume containing the entry, if the archive is split
+	Part int `json:"part,omitempty"`
+	archiveEntryFlags
+}
+
+// archiveEntryFlags record how the content of an archive entry differs from the raw log, so a
+// reviewer knows whether it is complete.
+type archiveEntryFlags struct {
+	Truncated bool `json:"truncated,omitempty"`
+	Redacted  bool `json:"redacted,omitempty"`
+	Filtered  bool `json:"filtered,omitempty"`
+	LineCount int  `json:"lineCount"`
+}
+
 func init() {
 	RootCmd.AddCommand(logsCmd)
 	logsCmd.Flags().StringVarP(&logType, "log", "l", logTypeAuto, "Trident log to display. One of trident|auto|all")
 	logsCmd.Flags().BoolVarP(&archive, "archive", "a", false, "Create a support archive with all logs unless otherwise specified.")
 	logsCmd.Flags().BoolVarP(&previous, "previous", "p", false, "Get the logs for the previous container instance if it exists.")
-	logsCmd.Flags().StringVar(&node, "node", "", "The kubernetes node name to gather node pod logs from.")
-	logsCmd.Flags().BoolVar(&sidecars, "sidecars", false, "Get the logs for the sidecar containers as well.")
+	logsCmd.Flags().BoolVar(&noPrevious, "no-previous", false, "Never get the logs for the previous container instances, even in archive mode or when a current log is empty.")
+	logsCmd.Flags().BoolVar(&noFallback, "no-fallback", false, "In auto mode, do not get the logs for the previous container instance when the current logs are empty.")
+	logsCmd.Flags().Int64Var(&tailLines, "tail", -1, "Lines of recent log to get from each container in console and archive modes. Defaults to -1, all lines.")
+	logsCmd.Flags().StringVar(&since, "since", "", "Get only log entries newer than this duration, e.g. 30m or 2h.")
+	logsCmd.Flags().StringVar(&sinceTime, "since-time", "", "Get only log entries after this RFC3339 time, e.g. 2020-01-20T15:04:05Z.")
+	logsCmd.Flags().Int64Var(&maxLogBytes, "max-log-bytes", 0, "The maximum size of each container log, beyond which it is truncated. Defaults to 0, no limit.")
+	logsCmd.Flags().IntVar(&logsRetries, "retry", 0, "The number of times to retry getting a container log after a transient failure, with exponential backoff.")
+	logsCmd.Flags().DurationVar(&requestTimeout, "request-timeout", 0, "The time to wait for each Kubernetes CLI command, such as one getting a container log, before giving up on it, e.g. 30s. Defaults to 0, no timeout.")
+	logsCmd.Flags().BoolVar(&timestamps, "timestamps", false, "Prefix each log line with its time, including in the archive.")
+	logsCmd.Flags().IntVar(&parallelism, "parallelism", 8, "The maximum number of node pods from which logs are collected at once.")
+	logsCmd.Flags().StringVar(&grepPattern, "grep", "", "Keep only the container log lines matching this regular expression.")
+	logsCmd.Flags().BoolVar(&invertMatch, "invert-match", false, "With --grep, keep only the container log lines not matching the regular expression.")
+	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Stream the logs until interrupted. Lines from multiple pods are prefixed with the pod name.")
+	logsCmd.Flags().StringArrayVar(&nodes, "node", []string{}, "The kubernetes node name to gather node pod logs from. May be repeated or a comma-separated list.")
+	logsCmd.Flags().StringVar(&KubernetesConfig, "kubeconfig", "", "The kubeconfig file of the cluster from which to gather logs. Defaults to the Kubernetes CLI default.")
+	logsCmd.Flags().StringVar(&KubernetesCLIOverride, "cli", "", "The Kubernetes CLI with which to gather logs. One of kubectl|oc (default discovered)")
+	logsCmd.Flags().StringVar(&KubernetesContext, "context", "", "The kubeconfig context of the cluster from which to gather logs. Defaults to the current context.")
+	logsCmd.Flags().StringVar(&nodeSelector, "selector", "", "A label selector of the kubernetes nodes to gather node pod logs from, e.g. topology.kubernetes.io/zone=us-east-1a.")
+	logsCmd.Flags().StringVar(&nodePattern, "node-pattern", "", "A shell glob or regular expression matching the names of the kubernetes nodes to gather node pod logs from, e.g. 'worker-*'.")
+	logsCmd.Flags().StringArrayVar(&excludedNodes, "exclude-node", []string{}, "The kubernetes node name to skip when gathering node pod logs. May be repeated or a comma-separated list.")
+	logsCmd.Flags().StringVarP(&logsContainer, "container", "c", "", "The container in the Trident pods to gather logs from, instead of the main Trident container.")
+	logsCmd.Flags().BoolVarP(&logsVerbose, "verbose", "v", false, "Print each command run to collect the logs to stderr.")
+	logsCmd.Flags().BoolVarP(&logsQuiet, "quiet", "q", false, "Print only the support archive path and any errors, not the progress of the collection.")
+	logsCmd.Flags().BoolVar(&sidecars, "sidecars", false, "Get the logs for the sidecar and init containers as well.")
+	logsCmd.Flags().StringVar(&archiveFormat, "format", archiveFormatZip, "Format of the support archive. One of zip|tgz")
+	logsCmd.Flags().StringVar(&outputDir, "output-dir", "", "Directory in which to write the support archive, created if necessary. Defaults to the current directory.")
+	logsCmd.Flags().StringVar(&logsOutDir, "out-dir", "", "In console mode, write each log to a separate file in this directory, created if necessary, instead of printing it.")
+	logsCmd.Flags().StringVar(&archiveName, "filename", "", "Name of the support archive, instead of one based on the time. The archive format extension is added if missing.")
+	logsCmd.Flags().BoolVar(&forceArchive, "force", false, "With --filename, overwrite an existing file.")
+	logsCmd.Flags().StringVar(&splitSize, "split-size", "", "Split the support archive into numbered volumes of about this size, e.g. 25MB, each a complete archive.")
+	logsCmd.Flags().IntVar(&compressThreshold, "compress-threshold", defaultCompressThreshold, "Archive entries smaller than this many bytes are stored without compression.")
+	logsCmd.Flags().IntVar(&compressionLevel, "compression-level", flate.DefaultCompression, "The compression level of the support archive, from 0, which stores the entries uncompressed, to 9, the smallest but slowest. The default balances size and speed.")
+	logsCmd.Flags().StringVar(&containerRuntime, "runtime", "", "Container runtime of a Trident running outside Kubernetes. One of docker|podman. Discovered automatically if not specified.")
+	logsCmd.Flags().StringVar(&runtimeContainer, "runtime-container", config.OrchestratorName, "Name of the Trident container when running outside Kubernetes.")
+	logsCmd.Flags().StringVar(&teeFileName, "tee", "", "Also write the console output to this file.")
+	logsCmd.Flags().StringVar(&colorMode, "color", colorAuto, "Color the console log lines by level. One of auto|always|never")
+	logsCmd.Flags().BoolVar(&pager, "pager", false, "Page the console output with $PAGER when writing to a terminal.")
+	logsCmd.Flags().StringVar(&aroundTime, "around", "", "Collect only log entries near this RFC3339 time, e.g. 2020-01-20T15:04:05Z.")
+	logsCmd.Flags().DurationVar(&aroundWindow, "window", 15*time.Minute, "With --around, collect log entries this long before and after the specified time.")
+	logsCmd.Flags().BoolVar(&fromEvents, "from-events", false, "Collect only log entries from the span of the Warning events in the Trident namespace, or from the last hour if there are none.")
+	logsCmd.Flags().StringVar(&ownedBy, "owned-by", "", "Collect the logs of all pods owned by this workload, e.g. deployment/trident-csi.")
+	logsCmd.Flags().BoolVar(&estimate, "estimate", false, "Print the approximate size of each log that would be collected, estimated from a sample of its recent lines and the log size reported by the kubelet, without collecting or writing anything. Filters are not applied to the estimate.")
+	logsCmd.Flags().StringArrayVar(&masks, "mask", []string{}, "A sensitive string to replace wherever it appears in the collected logs. May be repeated.")
+	logsCmd.Flags().BoolVar(&startupArgs, "startup-args", false, "Also collect the command and arguments of each container in the Trident pods.")
+	logsCmd.Flags().BoolVar(&splitByLevel, "split-by-level", false, "In archive mode, also aggregate all container logs into errors.log, warnings.log, info.log (info and debug) and other.log.")
+	logsCmd.Flags().BoolVar(&levelsOnly, "levels-only", false, "In archive mode, write only the aggregated level logs instead of per-container logs.")
+	logsCmd.Flags().StringVar(&logsPVName, "pv", "", "Collect the controller and node logs relevant to this persistent volume.")
+	logsCmd.Flags().StringVar(&junitFileName, "junit", "", "Also write the outcome of each container log collection to this file as JUnit XML.")
+	logsCmd.Flags().BoolVar(&storageClassDetail, "sc-detail", false, "Also collect the Trident CSIDriver and the settings of the storage classes using Trident.")
+	logsCmd.Flags().BoolVar(&groupByArray, "group-by-array", false, "In archive mode, also collect the log lines relevant to each storage array under arrays/.")
+	logsCmd.Flags().BoolVar(&describePods, "describe", false, "Also collect the description of each pod whose logs are collected. On by default in archive auto mode.")
+	logsCmd.Flags().BoolVar(&collectEvents, "events", false, "Also collect the events in the Trident namespace. On by default in archive auto mode.")
+	logsCmd.Flags().BoolVar(&tridentCRs, "crs", false, "Also collect the Trident custom resources, such as backends and volumes. On by default in archive auto mode.")
+	logsCmd.Flags().BoolVar(&operatorLogs, "operator", false, "Also collect the logs of the Trident operator pod. On by default in archive auto mode.")
+	logsCmd.Flags().BoolVar(&tlsInfo, "tls-info", false, "Also collect the issuer, names, and expiry of the Trident HTTPS REST certificates.")
+	logsCmd.Flags().BoolVar(&tridentConfig, "trident-config", false, "Also collect the Trident backend, storage class, and version configuration.")
+	logsCmd.Flags().StringVar(&configDiffBaseline, "config-diff", "", "Also collect the changes to the Trident configuration since it was collected in this support archive.")
+	logsCmd.Flags().BoolVar(&autoExpand, "auto-expand", false, "Also collect the logs of nodes named in errors in the Trident controller log.")
+	logsCmd.Flags().IntVar(&autoExpandMax, "auto-expand-max", 3, "The maximum number of node logs collected by --auto-expand.")
+	logsCmd.Flags().BoolVar(&redact, "redact", false, "Redact passwords, secrets, tokens, and private keys from the collected logs.")
+	logsCmd.Flags().BoolVar(&redactionReport, "redaction-report", false, "With --redact, also write a report of how many values of each kind were redacted from each log.")
+	logsCmd.Flags().IntVar(&goroutineID, "goroutine", 0, "Collect only the stack traces and log lines of the goroutine with this ID.")
+	logsCmd.Flags().BoolVar(&decodeBase64, "decode-base64", false, "Also collect the decoded text of long base64 strings in each log under decoded/.")
+	logsCmd.Flags().Float64Var(&logsRate, "rate", 0, "The maximum number of container logs requested per second, e.g. 2 on a busy cluster. Unlimited if not specified. To limit the bytes transferred instead, use --rate-limit.")
+	logsCmd.Flags().StringVar(&notifyURL, "notify", "", "POST a JSON summary of the collection to this webhook URL when done.")
+	logsCmd.Flags().StringVar(&caseID, "case-id", "", "A support case ID to include in the collection summary.")
+	logsCmd.Flags().StringVar(&logsConfigFile, "config", "", "A YAML file containing logs command options. Explicit flags override file values.")
+	logsCmd.Flags().BoolVar(&apiAccess, "api-access", false, "Extract the REST API access lines (logged at debug level) from the Trident controller log.")
+	logsCmd.Flags().BoolVar(&nodesOnly, "nodes-only", false, "Skip the Trident controller and collect only node pod logs.")
+	logsCmd.Flags().BoolVar(&nodeOnly, "node-only", false, "Collect only the logs of the Trident node pods. The same as --nodes-only.")
+	logsCmd.Flags().BoolVar(&controllerOnly, "controller-only", false, "Collect only the logs of the Trident controller. The same as --log trident.")
+	logsCmd.Flags().BoolVar(&apiErrorsOnly, "api-errors-only", false, "With --api-access, keep only REST API calls that did not return a 2xx status.")
 }
 
 var logsCmd = &cobra.Command{
@@ -53,211 +383,3581 @@ var logsCmd = &cobra.Command{
 	Short: "Print the logs from Trident",
 	Long:  "Print the logs from the Trident storage orchestrator for Kubernetes",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyLogsConfigFile(cmd); err != nil {
+			return err
+		}
+		if err := checkValidLogTimes(); err != nil {
+			return err
+		}
+		if KubernetesConfig != "" {
+			if _, err := os.Stat(KubernetesConfig); err != nil {
+				return fmt.Errorf("could not read kubeconfig file; %v", err)
+			}
+		}
+		// A Trident running outside Kubernetes needs no pod discovery
+		if containerRuntime != "" {
+			return nil
+		}
+		if allNamespaces {
+			return discoverAllNamespaces()
+		}
 		err := discoverOperatingMode(cmd)
 		return err
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 
+		describePodsSet = cmd.Flags().Changed("describe")
+		collectEventsSet = cmd.Flags().Changed("events")
+		operatorLogsSet = cmd.Flags().Changed("operator")
+		tridentCRsSet = cmd.Flags().Changed("crs")
+		previousSet = cmd.Flags().Changed("previous")
+		sidecarsSet = cmd.Flags().Changed("sidecars")
+		archiveIndexArgs = archiveIndexFlags(cmd.Flags())
+
 		err := checkValidLog()
 		if err != nil {
 			return err
 		}
 
-		if archive {
-			return archiveLogs()
-		} else {
-			return consoleLogs()
-		}
-	},
+		if OperatingMode == ModeTunnel && !skipRBACCheck {
+			if err = checkRBACPermissions(); err != nil {
+				return err
+			}
+		}
+
+		// In a pod, the service account always provides credentials for the Kubernetes API
+		if (useLogsAPI || InCluster) && OperatingMode == ModeTunnel {
+			initLogsAPIClient()
+		}
+
+		if estimate {
+			err = estimateLogs()
+		} else if archive {
+			// Only the archive itself may be written to stdout, so everything else goes to stderr
+			if archiveToStdout {
+				defer redirectStdoutToStderr()()
+			}
+			if err = archiveLogs(); err == nil && (uploadS3 != "" || uploadURL != "") {
+				err = uploadArchive()
+			}
+		} else {
+			err = consoleLogs()
+		}
+
+		if OutputFormat == FormatJSON {
+			results := collectionResults
+			if results == nil {
+				results = make([]collectionResult, 0)
+			}
+			WriteJSON(results)
+		}
+
+		if junitFileName != "" {
+			if junitErr := writeJUnitResults(junitFileName, collectionResults); junitErr != nil && err == nil {
+				err = junitErr
+			}
+		}
+
+		if notifyURL != "" {
+			if notifyErr := postCollectionSummary(notifyURL, buildCollectionSummary(err)); notifyErr != nil {
+				fmt.Fprintf(os.Stderr, "Could not send the collection summary; %v\n", notifyErr)
+			}
+		}
+
+		// Distinguish a degraded collection from one that collected nothing
+		if !follow {
+			if code := collectionExitCode(collectionResults, err); err != nil {
+				err = &exitCodeError{error: err, code: code}
+			} else {
+				ExitCode = code
+			}
+		}
+
+		return err
+	},
+}
+
+// collectionExitCode returns the exit code of a log collection: success if every log was
+// collected, a partial failure if only some were, and a total failure if none were.  Any other
+// error is a general failure.
+func collectionExitCode(results []collectionResult, err error) int {
+
+	var collected, failed int
+	for _, result := range results {
+		if result.Error == "" {
+			collected++
+		} else {
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0 && err == nil:
+		return ExitCodeSuccess
+	case collected == 0 && (failed > 0 || err != nil):
+		return ExitCodeTotalFailure
+	case failed > 0:
+		return ExitCodePartialFailure
+	default:
+		return ExitCodeFailure
+	}
+}
+
+// applyLogsConfigFile reads logs command options from the YAML file specified with --config and
+// applies each one that was not explicitly set on the command line.  Every key in the file must
+// be the name of a logs command flag.
+func applyLogsConfigFile(cmd *cobra.Command) error {
+
+	if logsConfigFile == "" {
+		return nil
+	}
+
+	configBytes, err := ioutil.ReadFile(logsConfigFile)
+	if err != nil {
+		return fmt.Errorf("could not read config file %s; %v", logsConfigFile, err)
+	}
+
+	options, err := parseLogsConfig(configBytes)
+	if err != nil {
+		return fmt.Errorf("could not parse config file %s; %v", logsConfigFile, err)
+	}
+
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Validate all keys before applying any of them
+	flags := cmd.Flags()
+	var unknownKeys []string
+	for _, key := range keys {
+		if key == "config" || flags.Lookup(key) == nil {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	if len(unknownKeys) > 0 {
+		return fmt.Errorf("config file %s contains unknown option(s): %s", logsConfigFile,
+			strings.Join(unknownKeys, ", "))
+	}
+
+	for _, key := range keys {
+		if flags.Lookup(key).Changed {
+			continue
+		}
+		for _, value := range options[key] {
+			if err := flags.Set(key, value); err != nil {
+				return fmt.Errorf("invalid value for option %s in config file %s; %v", key, logsConfigFile, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseLogsConfig converts a YAML document of option names and values into a map of option names
+// to flag values.  Lists are returned as one value per element, so they may be used with
+// repeatable flags.
+func parseLogsConfig(configBytes []byte) (map[string][]string, error) {
+
+	jsonBytes, err := yaml.YAMLToJSON(configBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	if err = decoder.Decode(&document); err != nil {
+		return nil, errors.New("the config file must contain a map of option names to values")
+	}
+
+	options := make(map[string][]string, len(document))
+	for key, value := range document {
+		switch v := value.(type) {
+		case []interface{}:
+			values := make([]string, 0, len(v))
+			for _, element := range v {
+				elementValue, err := logsConfigValue(key, element)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, elementValue)
+			}
+			options[key] = values
+		default:
+			optionValue, err := logsConfigValue(key, v)
+			if err != nil {
+				return nil, err
+			}
+			options[key] = []string{optionValue}
+		}
+	}
+
+	return options, nil
+}
+
+func logsConfigValue(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool, json.Number:
+		return fmt.Sprintf("%v", v), nil
+	case nil:
+		return "", fmt.Errorf("option %s has no value", key)
+	default:
+		return "", fmt.Errorf("option %s must be a scalar value or a list of scalar values", key)
+	}
+}
+
+func writeLogs(logName string, logEntry []byte) error {
+
+	var flags archiveEntryFlags
+
+	logEntry, selected := selectLogLines(logEntry, &flags)
+	if !selected {
+		return nil
+	}
+
+	return writeSelectedLogs(logName, logEntry, flags)
+}
+
+// selectLogLines keeps the lines of a container log about the requested goroutine and matching
+// --grep, if specified, noting the filtering in the entry flags.  A log that does not mention the
+// goroutine is not selected at all.
+func selectLogLines(logEntry []byte, flags *archiveEntryFlags) ([]byte, bool) {
+
+	// Skip container logs that do not mention the requested goroutine
+	if goroutineID > 0 {
+		if logEntry = extractGoroutine(logEntry, goroutineID); len(logEntry) == 0 {
+			return nil, false
+		}
+		goroutineFound = true
+		flags.Filtered = true
+	}
+
+	if grepRegex != nil {
+		logEntry = grepLogLines(logEntry, grepRegex, invertMatch)
+		flags.Filtered = true
+	}
+
+	return logEntry, true
+}
+
+// writeSelectedLogs transforms and writes a container log whose lines were already selected.
+func writeSelectedLogs(logName string, logEntry []byte, flags archiveEntryFlags) error {
+
+	logName, logEntry = transformLogEntry(logName, logEntry, &flags)
+
+	if maxLogBytes > 0 {
+		var truncated bool
+		if logEntry, truncated = truncateLogEntry(logEntry, maxLogBytes); truncated {
+			flags.Truncated = true
+		}
+	}
+
+	if decodeBase64 {
+		writeDecodedBase64(logName, logEntry)
+	}
+
+	if groupByArray && archive && !estimate {
+		arrayLogSources[logName] = logEntry
+	}
+
+	// Container logs may also be aggregated by level across all containers
+	if splitByLevel && archive && !estimate {
+		addToLevelLogs(logName, logEntry)
+		if levelsOnly {
+			return nil
+		}
+	}
+
+	return writeTransformedLogEntry(logName, logEntry, flags)
+}
+
+// truncateLogEntry cuts a log down to at most the maximum size, ending on a line boundary if
+// possible, and appends a marker noting how many bytes were dropped.
+func truncateLogEntry(logEntry []byte, maxBytes int64) ([]byte, bool) {
+
+	if int64(len(logEntry)) <= maxBytes {
+		return logEntry, false
+	}
+
+	kept := logEntry[:maxBytes]
+	if lastNewline := bytes.LastIndexByte(kept, '\n'); lastNewline >= 0 {
+		kept = kept[:lastNewline+1]
+	}
+
+	truncatedEntry := make([]byte, 0, len(kept)+32)
+	truncatedEntry = append(truncatedEntry, kept...)
+	if len(kept) > 0 && kept[len(kept)-1] != '\n' {
+		truncatedEntry = append(truncatedEntry, '\n')
+	}
+	truncatedEntry = append(truncatedEntry, fmt.Sprintf("... [truncated %d bytes]\n", len(logEntry)-len(kept))...)
+
+	return truncatedEntry, true
+}
+
+// writeLogEntry applies any requested transformations to a collected log, noting each one in
+// the entry flags, and then writes it to the archive or console.
+func writeLogEntry(logName string, logEntry []byte, flags archiveEntryFlags) error {
+	logName, logEntry = transformLogEntry(logName, logEntry, &flags)
+	return writeTransformedLogEntry(logName, logEntry, flags)
+}
+
+// transformLogEntry applies any requested transformations to a collected log and its name,
+// noting each one in the entry flags.
+func transformLogEntry(logName string, logEntry []byte, flags *archiveEntryFlags) (string, []byte) {
+
+	if !logUntilTime.IsZero() {
+		logEntry = filterLogsUntil(logEntry, logUntilTime, timestamps)
+	}
+	if !logSinceTime.IsZero() || !logUntilTime.IsZero() {
+		flags.Filtered = true
+	}
+
+	if len(lineFilters) > 0 {
+		logEntry = filterLogLines(logEntry, lineFilters)
+		flags.Filtered = true
+	}
+
+	if maskReplacer != nil {
+		maskedEntry := maskReplacer.Replace(string(logEntry))
+		if maskedEntry != string(logEntry) {
+			logEntry = []byte(maskedEntry)
+			flags.Redacted = true
+		}
+		logName = maskReplacer.Replace(logName)
+	}
+
+	if redact {
+		var redacted bool
+		if logEntry, redacted = redactLog(logName, logEntry); redacted {
+			flags.Redacted = true
+		}
+	}
+
+	flags.LineCount = countLines(logEntry)
+
+	return logName, logEntry
+}
+
+var (
+	goroutineHeaderRegex = regexp.MustCompile(`^goroutine (\d+) \[[^\]]*\]:`)
+	goroutinePanicRegex  = regexp.MustCompile(`^(panic: |fatal error: )`)
+)
+
+// extractGoroutine returns the stack traces of the specified goroutine found in a log, along
+// with any panic message that precedes them and any other lines that mention the goroutine.
+// A stack trace starts with a "goroutine N [state]:" line and ends with a blank line.
+func extractGoroutine(logEntry []byte, id int) []byte {
+
+	mentionRegex := regexp.MustCompile(fmt.Sprintf(`\bgoroutine %d\b`, id))
+	idString := strconv.Itoa(id)
+
+	var extracted bytes.Buffer
+	var panicLines []string
+	inStack, inRequestedStack := false, false
+
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		_, message := splitLogTimestamp(line)
+
+		if match := goroutineHeaderRegex.FindStringSubmatch(message); match != nil {
+			inStack = true
+			inRequestedStack = match[1] == idString
+			if inRequestedStack {
+				for _, panicLine := range panicLines {
+					extracted.WriteString(panicLine)
+				}
+			}
+			panicLines = nil
+		} else if inStack && strings.TrimSpace(message) == "" {
+			if inRequestedStack {
+				extracted.WriteString(line)
+			}
+			inStack, inRequestedStack = false, false
+			continue
+		} else if !inStack && goroutinePanicRegex.MatchString(message) {
+			panicLines = []string{line}
+			continue
+		} else if !inStack && len(panicLines) > 0 && strings.TrimSpace(message) == "" {
+			panicLines = append(panicLines, line)
+			continue
+		}
+
+		if inRequestedStack || (!inStack && mentionRegex.MatchString(message)) {
+			extracted.WriteString(line)
+		}
+	}
+
+	return extracted.Bytes()
+}
+
+// redactionPattern matches a kind of sensitive value.  The first submatch, if any, is kept so
+// that the redacted log still shows which field held the value.
+type redactionPattern struct {
+	name  string
+	regex *regexp.Regexp
+}
+
+var redactionPatterns = []redactionPattern{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"password", regexp.MustCompile(`(?i)(password\\?["']?\s*[:=]\s*\\?["']?)[^"'\s,}\\]+`)},
+	{"secret", regexp.MustCompile(`(?i)(secret\\?["']?\s*[:=]\s*\\?["']?)[^"'\s,}\\]+`)},
+	{"token", regexp.MustCompile(`(?i)(token\\?["']?\s*[:=]\s*\\?["']?)[^"'\s,}\\]+`)},
+	{"bearer token", regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)},
+}
+
+const redactedValue = "<REDACTED>"
+
+// minBase64TokenLength is the length below which strings are too likely to be ordinary words or
+// identifiers to be worth decoding.
+const minBase64TokenLength = 40
+
+var base64TokenRegex = regexp.MustCompile(fmt.Sprintf(`[A-Za-z0-9+/]{%d,}={0,2}`, minBase64TokenLength))
+
+// decodeBase64Tokens decodes the base64 tokens in a log that decode to printable text, returning
+// each one with the number of the line where it was found.  Tokens that decode to binary data
+// are skipped, since they are more likely hashes or paths than encoded payloads.
+func decodeBase64Tokens(logEntry []byte) []byte {
+
+	var decoded bytes.Buffer
+
+	for lineNumber, line := range strings.Split(string(logEntry), "\n") {
+		for _, token := range base64TokenRegex.FindAllString(line, -1) {
+			encoding := base64.StdEncoding
+			if !strings.HasSuffix(token, "=") && len(token)%4 != 0 {
+				encoding = base64.RawStdEncoding
+			}
+			value, err := encoding.DecodeString(token)
+			if err != nil || !isPrintableText(value) {
+				continue
+			}
+			fmt.Fprintf(&decoded, "line %d: %s\n%s\n\n", lineNumber+1, token, strings.TrimRight(string(value), "\n"))
+		}
+	}
+
+	return decoded.Bytes()
+}
+
+func isPrintableText(value []byte) bool {
+	if !utf8.Valid(value) {
+		return false
+	}
+	for _, r := range string(value) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeDecodedBase64 writes the decoded base64 tokens of a transformed log, if any, as a
+// companion entry.  Tokens masked by --redact are already gone, and anything sensitive inside
+// the decoded values is likewise redacted.
+func writeDecodedBase64(logName string, logEntry []byte) {
+
+	decoded := decodeBase64Tokens(logEntry)
+	if len(decoded) == 0 {
+		return
+	}
+
+	decodedName := "decoded/" + logName
+	var flags archiveEntryFlags
+	if maskReplacer != nil {
+		if masked := maskReplacer.Replace(string(decoded)); masked != string(decoded) {
+			decoded, flags.Redacted = []byte(masked), true
+		}
+	}
+	if redact {
+		var redacted bool
+		if decoded, redacted = redactLog(decodedName, decoded); redacted {
+			flags.Redacted = true
+		}
+	}
+	flags.LineCount = countLines(decoded)
+
+	if err := writeTransformedLogEntry(decodedName, decoded, flags); err != nil {
+		writeError := fmt.Sprintf("could not write log %s; %v", decodedName, err)
+		logErrors.add(writeError)
+	}
+}
+
+// redactLog replaces the sensitive values in a log, counting the matches of each pattern for
+// the redaction report, and returns the redacted log and whether anything was redacted.
+func redactLog(logName string, logEntry []byte) ([]byte, bool) {
+
+	redacted := false
+	for _, pattern := range redactionPatterns {
+		matches := len(pattern.regex.FindAllIndex(logEntry, -1))
+		if matches == 0 {
+			continue
+		}
+		redacted = true
+		logEntry = pattern.regex.ReplaceAll(logEntry, []byte("${1}"+redactedValue))
+
+		if redactionCounts[pattern.name] == nil {
+			redactionCounts[pattern.name] = make(map[string]int)
+		}
+		redactionCounts[pattern.name][logName] += matches
+	}
+
+	return logEntry, redacted
+}
+
+// writeRedactionReport writes the number of values redacted from each log by each pattern.
+func writeRedactionReport() {
+
+	var report bytes.Buffer
+	table := tablewriter.NewWriter(&report)
+	table.SetHeader([]string{"Pattern", "Log", "Redacted"})
+
+	for _, pattern := range redactionPatterns {
+		logNames := make([]string, 0, len(redactionCounts[pattern.name]))
+		for logName := range redactionCounts[pattern.name] {
+			logNames = append(logNames, logName)
+		}
+		sort.Strings(logNames)
+		for _, logName := range logNames {
+			table.Append([]string{pattern.name, logName, strconv.Itoa(redactionCounts[pattern.name][logName])})
+		}
+	}
+	table.Render()
+
+	if len(redactionCounts) == 0 {
+		report.WriteString("No values were redacted.\n")
+	}
+
+	flags := archiveEntryFlags{LineCount: countLines(report.Bytes())}
+	if err := writeTransformedLogEntry(logNameRedaction, report.Bytes(), flags); err != nil {
+		writeError := fmt.Sprintf("could not write log %s; %v", logNameRedaction, err)
+		logErrors.add(writeError)
+	}
+}
+
+// writeTransformedLogEntry writes a log to the archive, console or --out-dir file, or records
+// its size if only estimating.
+func writeTransformedLogEntry(logName string, logEntry []byte, flags archiveEntryFlags) error {
+	if !archiveEntrySelected(logName) {
+		return nil
+	}
+	if OutputFormat == FormatNDJSON && !archive && !estimate {
+		return writeNDJSONLines(consoleOutput, logName, logEntry)
+	}
+	logName = logNamespacePrefix + logName
+	if estimate {
+		logEstimates = append(logEstimates, logEstimate{Name: logName, Lines: flags.LineCount, Bytes: len(logEntry),
+			Basis: estimateBasisExact})
+	} else if archive {
+		if err := writeArchiveEntry(logName, logEntry, flags); err != nil {
+			return err
+		}
+		printArchiveProgress("Wrote %s log to %s archive file.\n", logName, zipFileName)
+	} else if logsOutDir != "" {
+		logFile, err := createLogFile(logName)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+		if _, err = logFile.Write(logEntry); err != nil {
+			return err
+		}
+		if !logsQuiet {
+			fmt.Fprintf(consoleOutput, "Wrote %s log to %s.\n", logName, logFile.Name())
+		}
+	} else {
+		if colorize {
+			logEntry = colorizeLogLines(logEntry)
+		}
+		fmt.Fprintf(consoleOutput, "%s log:\n", logName)
+		fmt.Fprintf(consoleOutput, "%s\n", string(logEntry))
+	}
+	return nil
+}
+
+// createLogFile creates the file in the --out-dir directory to which a log is written, along
+// with any directory named by the log, such as that of the custom resources.
+func createLogFile(logName string) (*os.File, error) {
+	logFileName := filepath.Join(logsOutDir, filepath.FromSlash(logName))
+	if err := os.MkdirAll(filepath.Dir(logFileName), 0755); err != nil {
+		return nil, fmt.Errorf("could not create output directory %s; %v", filepath.Dir(logFileName), err)
+	}
+	return os.Create(logFileName)
+}
+
+// logLevelColors are the ANSI escape sequences that color console log lines by level.
+var logLevelColors = map[string]string{
+	"debug":   "\x1b[90m",
+	"info":    "\x1b[32m",
+	"warning": "\x1b[33m",
+	"error":   "\x1b[31m",
+	"fatal":   "\x1b[1;31m",
+	"panic":   "\x1b[1;31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// logLineColorizer colors log lines by level.  Lines without a level, such as those of a stack
+// trace, take the color of the line before them.
+type logLineColorizer struct {
+	color string
+}
+
+func (c *logLineColorizer) colorize(line string) string {
+
+	if level := parseLogLevel(line); level != "" {
+		c.color = logLevelColors[level]
+	}
+
+	text := strings.TrimRight(line, "\n")
+	if c.color == "" || text == "" {
+		return line
+	}
+	return c.color + text + colorReset + line[len(text):]
+}
+
+// colorizeLogLines colors each line of a log by level.
+func colorizeLogLines(logEntry []byte) []byte {
+
+	var colorizer logLineColorizer
+	var colored bytes.Buffer
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		colored.WriteString(colorizer.colorize(line))
+	}
+	return colored.Bytes()
+}
+
+// collectionSummary is the metadata sent to the --notify webhook after a collection.
+type collectionSummary struct {
+	Archive   string `json:"archive,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	CaseID    string `json:"caseID,omitempty"`
+	Success   bool   `json:"success"`
+	Collected int    `json:"collected"`
+	Failed    int    `json:"failed"`
+}
+
+// buildCollectionSummary summarizes the collection results and any archive that was written.
+func buildCollectionSummary(err error) collectionSummary {
+
+	summary := collectionSummary{CaseID: caseID}
+
+	for _, result := range collectionResults {
+		if result.Error == "" {
+			summary.Collected++
+		} else {
+			summary.Failed++
+		}
+	}
+	summary.Success = err == nil && summary.Failed == 0
+
+	if archive && zipFileName != "" {
+		archiveFiles := archiveFileNames()
+		summary.Archive = strings.Join(archiveFiles, ", ")
+		for _, fileName := range archiveFiles {
+			if fileInfo, statErr := os.Stat(fileName); statErr == nil {
+				summary.Size += fileInfo.Size()
+			}
+		}
+	}
+
+	return summary
+}
+
+// postCollectionSummary sends the collection summary to a webhook, retrying once on failure.
+// Proxy settings are taken from the environment.
+func postCollectionSummary(webhookURL string, summary collectionSummary) error {
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		var response *http.Response
+		var responseBody []byte
+		response, responseBody, err = api.InvokeRESTAPI("POST", webhookURL, summaryJSON, Debug)
+		if err == nil && (response.StatusCode < 200 || response.StatusCode >= 300) {
+			err = fmt.Errorf("webhook returned %s; %s", response.Status, strings.TrimSpace(string(responseBody)))
+		}
+		if err == nil || attempt > 1 {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnitResults writes the collection results as JUnit XML, with each container log
+// collection as a test case.
+func writeJUnitResults(fileName string, results []collectionResult) error {
+
+	testSuite := junitTestSuite{
+		Name:      "tridentctl logs",
+		Tests:     len(results),
+		Timestamp: time.Now().Format(time.RFC3339),
+		TestCases: make([]junitTestCase, 0, len(results)),
+	}
+
+	for _, result := range results {
+		testCase := junitTestCase{Name: result.Name, ClassName: result.Pod}
+		if testCase.ClassName == "" {
+			testCase.ClassName = result.Container
+		}
+		if result.Error != "" {
+			testCase.Failure = &junitFailure{Message: maskString(result.Error)}
+			testSuite.Failures++
+		}
+		testSuite.TestCases = append(testSuite.TestCases, testCase)
+	}
+
+	junitBytes, err := xml.MarshalIndent(junitTestSuites{TestSuites: []junitTestSuite{testSuite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(fileName, append([]byte(xml.Header), junitBytes...), 0644); err != nil {
+		return fmt.Errorf("could not write JUnit file %s; %v", fileName, err)
+	}
+
+	return nil
+}
+
+// expandArchiveLogType sets the options used to collect an archive in "auto" mode.
+func expandArchiveLogType() {
+
+	// In archive mode, "auto" means to attempt to get all logs (current & previous).
+	if logType == logTypeAuto {
+		logType = logTypeAll
+		if !previousSet && !noPrevious {
+			previous = true
+		}
+		if !sidecarsSet {
+			sidecars = true
+		}
+		if !describePodsSet {
+			describePods = true
+		}
+		if !collectEventsSet {
+			collectEvents = true
+		}
+		if !operatorLogsSet {
+			operatorLogs = true
+		}
+		if !tridentCRsSet {
+			tridentCRs = true
+		}
+	}
+}
+
+func archiveLogs() error {
+
+	expandArchiveLogType()
+	archiveStartTime, archiveVersion = time.Now(), nil
+
+	// Create archive file.
+	zipFileName = time.Now().Format(archiveFilenameFormat) + archiveExtension(archiveFormat)
+	if archiveName != "" {
+		zipFileName = archiveName
+		if !strings.HasSuffix(zipFileName, archiveExtension(archiveFormat)) {
+			zipFileName += archiveExtension(archiveFormat)
+		}
+	}
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("could not create output directory %s; %v", outputDir, err)
+		}
+		if !filepath.IsAbs(zipFileName) {
+			zipFileName = filepath.Join(outputDir, zipFileName)
+		}
+	}
+	if archivePassword != "" {
+		zipFileName += encryptedArchiveExtension
+	}
+	if archiveToStdout {
+		zipFileName = stdoutArchiveName
+	}
+
+	// The volumes of a split archive are closed by its writer
+	var closeArchiveOutput func() error
+	archiveVolumes = nil
+	if archiveSplitSize > 0 {
+		splitWriter, err := newSplitArchiveWriter(archiveFormat, archiveSplitSize,
+			func(volume int) (io.Writer, func() error, error) {
+				volumeName := archiveVolumeName(zipFileName, volume)
+				archiveVolumes = append(archiveVolumes, volumeName)
+				return createArchiveFile(volumeName)
+			})
+		if err != nil {
+			return err
+		}
+		archiveWriter = splitWriter
+	} else {
+		createArchive := createArchiveFile
+		if archiveToStdout {
+			createArchive = func(string) (io.Writer, func() error, error) { return createArchiveStdout() }
+		}
+		archiveOutput, closeOutput, err := createArchive(zipFileName)
+		if err != nil {
+			return err
+		}
+		if archiveWriter, err = newArchiveWriter(archiveFormat, archiveOutput); err != nil {
+			_ = closeOutput()
+			return err
+		}
+		closeArchiveOutput = closeOutput
+	}
+
+	// The archive is closed below once complete, so that a failure is reported; this only cleans
+	// up after an error
+	archiveClosed := false
+	defer func() {
+		if !archiveClosed {
+			_ = closeSupportArchive(archiveWriter, closeArchiveOutput)
+		}
+	}()
+
+	// On Ctrl-C, stop collecting and finish the archive with the logs already written, so that it
+	// remains readable.  A second Ctrl-C exits immediately.
+	var cancel context.CancelFunc
+	collectionContext, cancel = context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		collectionContext = context.Background()
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			fmt.Fprintln(os.Stderr, "Interrupted; finishing the support archive with the logs collected so far.")
+			cancel()
+		case <-collectionContext.Done():
+		}
+	}()
+
+	getLogs()
+
+	// The version of each install collected with --all-namespaces is written with its logs
+	interrupted := collectionContext.Err() != nil
+	if !interrupted && !(allNamespaces && OperatingMode == ModeTunnel) {
+		writeClusterState(logNameVersion, getVersionInfo)
+	}
+
+	if goroutineID > 0 && !goroutineFound {
+		notFound := fmt.Sprintf("goroutine %d was not found in any collected log", goroutineID)
+		logErrors.add(notFound)
+		printArchiveProgress("Goroutine %d was not found in any collected log.\n", goroutineID)
+	}
+
+	if splitByLevel {
+		writeLevelLogs()
+	}
+
+	if groupByArray {
+		writeArrayLogs()
+	}
+
+	if redactionReport {
+		writeRedactionReport()
+	}
+
+	if !logErrors.empty() {
+		maskedErrors := []byte(maskString(logErrors.String()))
+		if err := writeArchiveEntry("errors", maskedErrors, archiveEntryFlags{LineCount: countLines(maskedErrors)}); err != nil {
+			return err
+		}
+		printArchiveProgress("Wrote %s log to %s archive file.\n", "errors", zipFileName)
+	}
+
+	if err := writeArchiveManifest(); err != nil {
+		return err
+	}
+
+	if err := writeArchiveIndex(); err != nil {
+		return err
+	}
+
+	archiveClosed = true
+	if err := closeSupportArchive(archiveWriter, closeArchiveOutput); err != nil {
+		if archiveToStdout {
+			return fmt.Errorf("could not finish the support archive written to stdout; %v", err)
+		}
+		return fmt.Errorf("could not finish the support archive %s; %v", zipFileName, err)
+	}
+
+	if archiveToStdout {
+		if interrupted {
+			return errors.New("log collection was interrupted; the partial support archive written to stdout " +
+				"is complete and readable")
+		}
+		printArchiveResult("Support archive written to stdout.\n")
+		return nil
+	}
+
+	archiveFiles := archiveFileNames()
+	absFileNames := make([]string, 0, len(archiveFiles))
+	for _, fileName := range archiveFiles {
+		if absFileName, err := filepath.Abs(fileName); err == nil {
+			fileName = absFileName
+		}
+		absFileNames = append(absFileNames, fileName)
+	}
+	if interrupted {
+		return fmt.Errorf("log collection was interrupted; the partial support archive %s is complete "+
+			"and readable", strings.Join(absFileNames, ", "))
+	}
+	if len(absFileNames) == 1 {
+		printArchiveResult("Support archive written to %s.\n", absFileNames[0])
+	} else {
+		printArchiveResult("Support archive written to %d volumes: %s.\n", len(absFileNames),
+			strings.Join(absFileNames, ", "))
+	}
+	if archivePassword != "" {
+		for _, absFileName := range absFileNames {
+			printArchiveResult("Decrypt it with: openssl enc -d -aes-256-cbc -pbkdf2 -in %s -out %s\n",
+				absFileName, strings.TrimSuffix(absFileName, encryptedArchiveExtension))
+		}
+	}
+
+	return nil
+}
+
+// createArchiveFile creates a support archive file, encrypting it as it is written if a password
+// was specified, and returns its writer and a function closing it.
+func createArchiveFile(fileName string) (io.Writer, func() error, error) {
+
+	// A named archive may already exist, so only overwrite it if forced
+	createFlags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if archiveName != "" && !forceArchive {
+		createFlags |= os.O_EXCL
+	}
+	archiveFile, err := os.OpenFile(fileName, createFlags, 0666)
+	if os.IsExist(err) {
+		return nil, nil, fmt.Errorf("%s already exists; use --force to overwrite it", fileName)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return encryptArchiveOutput(archiveFile, archiveFile.Close)
+}
+
+// encryptArchiveOutput returns a writer encrypting the support archive as it is written to the
+// output, if a password was specified, and a function closing both.  The logs spooled for the
+// archive are encrypted too, so neither is on disk in the clear.
+func encryptArchiveOutput(output io.Writer, closeOutput func() error) (io.Writer, func() error, error) {
+
+	if archivePassword == "" {
+		return output, closeOutput, nil
+	}
+
+	encryptedOutput, err := newEncryptingWriter(output, archivePassword)
+	if err != nil {
+		_ = closeOutput()
+		return nil, nil, fmt.Errorf("could not encrypt the support archive; %v", err)
+	}
+	return encryptedOutput, func() error {
+		err := encryptedOutput.Close()
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// archiveVolumeName returns the file name of a numbered volume of a split support archive, such
+// as support-2020-01-20T15-04-05-UTC-part001.zip.
+func archiveVolumeName(fileName string, volume int) string {
+
+	extension := archiveExtension(archiveFormat)
+	if archivePassword != "" {
+		extension += encryptedArchiveExtension
+	}
+	return fmt.Sprintf("%s-part%03d%s", strings.TrimSuffix(fileName, extension), volume, extension)
+}
+
+// archiveFileNames returns the files of the support archive, which are its volumes if split.
+func archiveFileNames() []string {
+	if archiveToStdout {
+		return nil
+	} else if archiveSplitSize > 0 {
+		return archiveVolumes
+	}
+	return []string{zipFileName}
+}
+
+// archiveVolume returns the number of the volume being written, or zero if the support archive
+// is not split.
+func archiveVolume() int {
+	if splitWriter, ok := archiveWriter.(*splitArchiveWriter); ok {
+		return splitWriter.Volume()
+	}
+	return 0
+}
+
+// printArchiveProgress reports the progress of writing the support archive, unless quiet or the
+// collection results are to be written as JSON instead.
+func printArchiveProgress(format string, a ...interface{}) {
+	if !logsQuiet {
+		printArchiveResult(format, a...)
+	}
+}
+
+// printArchiveResult reports where the support archive was written, even if quiet, unless the
+// output format is JSON.
+func printArchiveResult(format string, a ...interface{}) {
+	if OutputFormat != FormatJSON {
+		fmt.Printf(format, a...)
+	}
+}
+
+// writeArchiveEntry adds an entry to the support archive, storing it uncompressed if it is
+// smaller than the compression threshold, and records it in the archive manifest.
+func writeArchiveEntry(entryName string, entryBytes []byte, flags archiveEntryFlags) error {
+
+	compression, err := archiveWriter.WriteEntry(entryName, entryBytes, len(entryBytes) >= compressThreshold)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(entryBytes)
+	archiveManifest = append(archiveManifest, archiveManifestEntry{
+		Name:              entryName,
+		Size:              len(entryBytes),
+		SHA256:            hex.EncodeToString(digest[:]),
+		Compression:       compression,
+		Part:              archiveVolume(),
+		archiveEntryFlags: flags,
+	})
+
+	return nil
+}
+
+// writeArchiveManifest adds a manifest describing every other entry to the support archive.
+func writeArchiveManifest() error {
+
+	manifestBytes, err := json.MarshalIndent(archiveManifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = archiveWriter.WriteEntry(archiveManifestName, manifestBytes, true)
+	return err
+}
+
+// estimateLogs estimates the size of each selected container log from a sample of it, and
+// records the size of the cluster state, and prints a table of the sizes.  In archive mode, the
+// logs an archive would contain are estimated.
+func estimateLogs() error {
+
+	if archive {
+		expandArchiveLogType()
+	}
+
+	err := getLogs()
+
+	var totalLines, totalBytes int
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Log", "Lines", "Estimated Size", "Basis"})
+	for _, logEstimate := range logEstimates {
+		totalLines += logEstimate.Lines
+		totalBytes += logEstimate.Bytes
+		table.Append([]string{
+			logEstimate.Name,
+			strconv.Itoa(logEstimate.Lines),
+			humanize.IBytes(uint64(logEstimate.Bytes)),
+			logEstimate.Basis,
+		})
+	}
+	table.SetFooter([]string{"Total (estimate)", strconv.Itoa(totalLines), humanize.IBytes(uint64(totalBytes)), ""})
+	table.Render()
+
+	if !logErrors.empty() {
+		fmt.Fprintf(os.Stderr, "Some logs could not be estimated: %s\n", logErrors.String())
+	}
+
+	SetExitCodeFromError(err)
+	return err
+}
+
+func consoleLogs() error {
+
+	var stdout io.Writer = os.Stdout
+	if pager {
+		pagerCmd, pagerInput, err := startPager()
+		if err != nil {
+			return err
+		}
+		if pagerCmd != nil {
+			stdout = pagerInput
+			defer func() {
+				_ = pagerInput.Close()
+				_ = pagerCmd.Wait()
+			}()
+		}
+	}
+	consoleOutput = stdout
+
+	if logsOutDir != "" {
+		if err := os.MkdirAll(logsOutDir, 0755); err != nil {
+			return fmt.Errorf("could not create output directory %s; %v", logsOutDir, err)
+		}
+	}
+
+	if follow {
+		var cancel context.CancelFunc
+		followContext, cancel = context.WithCancel(context.Background())
+		defer cancel()
+
+		// Stop the followed log streams on Ctrl-C
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+		go func() {
+			select {
+			case <-signals:
+				cancel()
+			case <-followContext.Done():
+			}
+		}()
+	}
+
+	if teeFileName != "" {
+		teeFile, err := os.Create(teeFileName)
+		if err != nil {
+			return fmt.Errorf("could not create tee file %s; %v", teeFileName, err)
+		}
+		defer teeFile.Close()
+		consoleOutput = io.MultiWriter(stdout, teeFile)
+	}
+
+	err := getLogs()
+	followWait.Wait()
+
+	if goroutineID > 0 && !goroutineFound {
+		fmt.Fprintf(consoleOutput, "Goroutine %d was not found in any collected log.\n", goroutineID)
+	}
+
+	if redactionReport {
+		writeRedactionReport()
+	}
+
+	SetExitCodeFromError(err)
+	if err != nil {
+		// Preserve anything written to stdout/stderr
+		logMessage := maskString(logErrors.String())
+		if len(logMessage) > 0 {
+			errMessage := strings.TrimSuffix(strings.TrimSpace(err.Error()), ".")
+			return fmt.Errorf("%s. %s", errMessage, logMessage)
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+// startPager starts the pager specified by $PAGER and returns it along with its input.  If
+// $PAGER is unset or stdout is not a terminal, no pager is started.
+func startPager() (*exec.Cmd, io.WriteCloser, error) {
+
+	pagerCommand := strings.Fields(os.Getenv("PAGER"))
+	if len(pagerCommand) == 0 || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, nil, nil
+	}
+
+	pagerCmd := exec.Command(pagerCommand[0], pagerCommand[1:]...)
+	pagerCmd.Stdout = os.Stdout
+	pagerCmd.Stderr = os.Stderr
+	pagerInput, err := pagerCmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = pagerCmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("could not start pager %s; %v", pagerCommand[0], err)
+	}
+
+	return pagerCmd, pagerInput, nil
+}
+
+func getLogs() error {
+
+	if OperatingMode != ModeTunnel {
+		return getContainerRuntimeLogs()
+	}
+
+	nodes = splitNodeNames(nodes)
+	excludedNodes = splitNodeNames(excludedNodes)
+
+	var err error
+	if allNamespaces {
+		err = getAllNamespaceLogs()
+	} else {
+		err = getNamespaceLogs()
+	}
+
+	// The estimate and followed streams have their own reporting
+	if !estimate && !follow && !logsQuiet {
+		writeCollectionSummaryTable(os.Stderr, collectionResults, skippedNodes)
+	}
+
+	return err
+}
+
+// getNamespaceLogs collects the logs and cluster state of the Trident install in the Trident
+// namespace.
+func getNamespaceLogs() error {
+
+	if err := checkSelectedSidecars(); err != nil {
+		return err
+	}
+
+	getClusterState()
+
+	err := getSelectedLogs()
+
+	if operatorLogs {
+		if operatorErr := getOperatorLogs(); operatorErr != nil && err == nil {
+			err = operatorErr
+		}
+	}
+
+	getNodeDiagnostics()
+
+	if describePods {
+		writePodDescriptions()
+	}
+
+	return err
+}
+
+// writeCollectionSummaryTable writes a table of the outcome of collecting each container log, so
+// the logs missing from a partially successful collection are easy to find.  The nodes skipped
+// with --exclude-node are listed too, so their absence is clearly intentional.
+func writeCollectionSummaryTable(w io.Writer, results []collectionResult, skipped []string) {
+
+	if len(results) == 0 && len(skipped) == 0 {
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Source", "Log", "Status", "Reason"})
+	for _, result := range results {
+		source := result.Pod + "/" + result.Container
+		status := "collected"
+		if result.Error != "" {
+			status = "failed"
+		}
+		table.Append([]string{source, result.Name, status, result.Error})
+	}
+	for _, nodeName := range skipped {
+		table.Append([]string{"node/" + nodeName, "trident-node-" + nodeName, "skipped", "excluded by --exclude-node"})
+	}
+	table.Render()
+}
+
+// getOperatorLogs collects the logs of the Trident operator pod in the Trident namespace.  An
+// operator that cannot be found is only an error if its logs were explicitly requested, since
+// Trident need not be installed by the operator.
+func getOperatorLogs() error {
+
+	operatorPod, err := getTridentPod(TridentPodNamespace, TridentOperatorLabel)
+	if err != nil {
+		if operatorLogsSet {
+			return fmt.Errorf("could not find the Trident operator pod in the %s namespace", TridentPodNamespace)
+		}
+		if Debug {
+			fmt.Printf("Skipping the Trident operator log; %v\n", err)
+		}
+		return nil
+	}
+
+	_, err = collectContainerLogs(logNameOperator, operatorPod, operatorContainerName, "", false)
+	if previous {
+		collectContainerLogs(logNameOperatorPrevious, operatorPod, operatorContainerName, "", true)
+	}
+
+	return err
+}
+
+// getSelectedLogs collects the container logs selected by the command options.
+func getSelectedLogs() error {
+
+	var err error
+
+	if logsPodName != "" {
+		return getNamedPodLogs()
+	}
+
+	if ownedBy != "" {
+		return getOwnedPodLogs()
+	}
+
+	if logsPVName != "" {
+		return getPVLogs()
+	}
+
+	if nodesOnly {
+		return getNodesOnlyLogs()
+	}
+
+	switch logType {
+	case logTypeTrident, logTypeAuto:
+		if len(nodes) == 0 {
+			err = getTridentLogs(logNameTrident)
+		} else {
+			err = getSelectedNodeLogs(logNameNode)
+		}
+	case logTypeAll:
+		getTridentLogs(logNameTrident)
+		getSelectedNodeLogs(logNameNode)
+	}
+
+	if previous {
+		switch logType {
+		case logTypeTrident, logTypeAuto:
+			if len(nodes) == 0 {
+				getTridentLogs(logNameTridentPrevious)
+			} else {
+				getSelectedNodeLogs(logNameNodePrevious)
+			}
+		case logTypeAll:
+			getTridentLogs(logNameTridentPrevious)
+			getSelectedNodeLogs(logNameNodePrevious)
+		}
+	}
+
+	if autoExpand {
+		autoIncludeNodeLogs()
+	}
+
+	return err
+}
+
+// writePodDescriptions writes the description of each pod in the Trident namespace from which logs
+// were collected, which shows restarts, resource limits, and recent events that the logs do not.
+// The pods of any other namespace collected with --all-namespaces are described with their own.
+func writePodDescriptions() {
+
+	described := make(map[string]bool)
+	for _, result := range collectionResults {
+		if result.Pod == "" || result.Namespace != TridentPodNamespace || described[result.Pod] {
+			continue
+		}
+		described[result.Pod] = true
+
+		logName := "describe-" + result.Pod
+		describeCommand := []string{"describe", "pod", result.Pod, "-n", result.Namespace}
+		printInvokedCommand(KubernetesCLI, describeCommand)
+
+		description, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(describeCommand...)...)
+		if err != nil {
+			logErrors.add(err.Error())
+			continue
+		}
+		if err = writeLogEntry(logName, description, archiveEntryFlags{}); err != nil {
+			writeError := fmt.Sprintf("could not write log %s; %v", logName, err)
+			logErrors.add(writeError)
+		}
+	}
+}
+
+// autoIncludeNodeLogs collects the logs of nodes referenced by errors in the Trident controller
+// log that were not otherwise collected, up to the --auto-expand-max limit.
+func autoIncludeNodeLogs() {
+
+	if len(controllerLog) == 0 {
+		return
+	}
+
+	tridentNodes, err := listTridentNodes(TridentPodNamespace, "")
+	if err != nil {
+		logErrors.add(fmt.Sprintf("could not list nodes to auto-include; %v", err))
+		return
+	}
+
+	collectedNodes := make(map[string]bool)
+	for _, result := range collectionResults {
+		if result.Node != "" {
+			collectedNodes[result.Node] = true
+		}
+	}
+
+	for _, nodeName := range findNodesInErrors(controllerLog, tridentNodes, autoExpandMax) {
+		if collectedNodes[nodeName] {
+			continue
+		}
+		pod := tridentNodes[nodeName]
+		nodeLogName := "auto-included/trident-node-" + nodeName
+		collectContainerLogs(nodeLogName, pod, config.ContainerTrident, nodeName, false)
+		if previous {
+			collectContainerLogs(nodeLogName+"-previous", pod, config.ContainerTrident, nodeName, true)
+		}
+	}
+}
+
+// findNodesInErrors returns the names of up to limit nodes that are mentioned in error lines of
+// a log, in order of their first mention.
+func findNodesInErrors(logEntry []byte, tridentNodes map[string]string, limit int) []string {
+
+	// Check longer names first so a node whose name contains another's is matched correctly
+	nodeNames := make([]string, 0, len(tridentNodes))
+	for nodeName := range tridentNodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Slice(nodeNames, func(i, j int) bool { return len(nodeNames[i]) > len(nodeNames[j]) })
+
+	found := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(string(logEntry), "\n") {
+		if len(found) >= limit {
+			break
+		}
+		if levelLogName(parseLogLevel(line)) != levelLogErrors {
+			continue
+		}
+		for _, nodeName := range nodeNames {
+			if strings.Contains(line, nodeName) {
+				if !seen[nodeName] {
+					seen[nodeName] = true
+					found = append(found, nodeName)
+				}
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+// ownerKinds maps the accepted --owned-by workload kinds to their Kubernetes kinds.
+var ownerKinds = map[string]string{
+	"deployment":  "Deployment",
+	"replicaset":  "ReplicaSet",
+	"daemonset":   "DaemonSet",
+	"statefulset": "StatefulSet",
+	"job":         "Job",
+}
+
+// parseOwnedBy splits a kind/name workload reference into its Kubernetes kind and name.
+func parseOwnedBy(owner string) (string, string, error) {
+
+	parts := strings.Split(owner, "/")
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("%s is not a valid workload; expected <kind>/<name>", owner)
+	}
+
+	kind, ok := ownerKinds[strings.ToLower(parts[0])]
+	if !ok {
+		return "", "", fmt.Errorf("%s is not a supported workload kind", parts[0])
+	}
+
+	return kind, parts[1], nil
+}
+
+// getOwnedPodLogs collects the logs of every container in every pod owned by the workload
+// specified with --owned-by, organizing the entries by pod.
+func getOwnedPodLogs() error {
+
+	kind, name, err := parseOwnedBy(ownedBy)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listPodsOwnedBy(kind, name, TridentPodNamespace)
+	if err != nil {
+		return fmt.Errorf("error listing pods owned by %s; %v", ownedBy, err)
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("could not find any pods owned by %s in the %s namespace", ownedBy, TridentPodNamespace)
+	}
+
+	prevValues := []bool{false}
+	if previous {
+		prevValues = append(prevValues, true)
+	}
+
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			for _, prev := range prevValues {
+				podLogName := pod.Name + "/" + container.Name
+				if prev {
+					podLogName += "-previous"
+				}
+
+				collectContainerLogs(podLogName, pod.Name, container.Name, pod.Spec.NodeName, prev)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getPVLogs resolves the claim, Trident volume, and nodes of the persistent volume specified
+// with --pv, and then collects the controller and node logs limited to lines that mention it.
+func getPVLogs() error {
+
+	var pv k8s.PersistentVolume
+	if err := getKubernetesObjects(&pv, "get", "pv", logsPVName, "-o=json"); err != nil {
+		return fmt.Errorf("could not get persistent volume %s; %v", logsPVName, err)
+	}
+
+	var attachments storagev1.VolumeAttachmentList
+	if err := getKubernetesObjects(&attachments, "get", "volumeattachment", "-o=json"); err != nil {
+		logErrors.add(fmt.Sprintf("could not list volume attachments; %v", err))
+	}
+
+	pvNote, filters, nodeNames := resolvePV(&pv, attachments.Items)
+	if err := writeLogEntry("pv-"+logsPVName+".txt", pvNote, archiveEntryFlags{}); err != nil {
+		logErrors.add(fmt.Sprintf("could not write PV details; %v", err))
+	}
+
+	// Only the Trident logs collected here are limited to the lines about the volume
+	lineFilters = filters
+	defer func() { lineFilters = nil }()
+
+	logNames := []string{logNameTrident}
+	nodeLogNames := []string{logNameNode}
+	if previous {
+		logNames = append(logNames, logNameTridentPrevious)
+		nodeLogNames = append(nodeLogNames, logNameNodePrevious)
+	}
+
+	var err error
+	for _, logName := range logNames {
+		if getErr := getTridentLogs(logName); getErr != nil && err == nil {
+			err = getErr
+		}
+	}
+	for _, nodeLogName := range nodeLogNames {
+		for _, nodeName := range nodeNames {
+			getNodeLogs(nodeLogName, nodeName)
+		}
+		if mergeErr := writeMergedNodeLog(nodeLogName); mergeErr != nil && err == nil {
+			err = mergeErr
+		}
+	}
+
+	return err
+}
+
+// resolvePV describes the claim, Trident volume, and attached nodes of a persistent volume,
+// and returns the describing note along with the strings that identify the volume in the logs
+// and the names of the nodes to which it is attached.
+func resolvePV(pv *k8s.PersistentVolume, attachments []storagev1.VolumeAttachment) ([]byte, []string, []string) {
+
+	var note bytes.Buffer
+	filters := []string{pv.Name}
+
+	fmt.Fprintf(&note, "PV: %s\n", pv.Name)
+	fmt.Fprintf(&note, "Phase: %s\n", pv.Status.Phase)
+
+	volumeName := pv.Name
+	if pv.Spec.CSI != nil {
+		volumeName = pv.Spec.CSI.VolumeHandle
+		fmt.Fprintf(&note, "CSI driver: %s\n", pv.Spec.CSI.Driver)
+	}
+	fmt.Fprintf(&note, "Trident volume: %s\n", volumeName)
+	if volumeName != pv.Name {
+		filters = append(filters, volumeName)
+	}
+
+	if pv.Spec.ClaimRef != nil && pv.Status.Phase == k8s.VolumeBound {
+		fmt.Fprintf(&note, "PVC: %s/%s\n", pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name)
+		filters = append(filters, pv.Spec.ClaimRef.Name)
+	} else if pv.Spec.ClaimRef != nil {
+		fmt.Fprintf(&note, "Note: the PV is %s and no longer bound to PVC %s/%s.\n",
+			pv.Status.Phase, pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name)
+	} else {
+		fmt.Fprintf(&note, "Note: the PV is %s and not bound to any PVC.\n", pv.Status.Phase)
+	}
+
+	var nodeNames []string
+	for _, attachment := range attachments {
+		source := attachment.Spec.Source.PersistentVolumeName
+		if source != nil && *source == pv.Name {
+			nodeNames = append(nodeNames, attachment.Spec.NodeName)
+		}
+	}
+	if len(nodeNames) > 0 {
+		fmt.Fprintf(&note, "Attached to nodes: %s\n", strings.Join(nodeNames, ", "))
+	} else {
+		fmt.Fprintf(&note, "Note: the PV is not attached to any node, so only the controller log is collected.\n")
+	}
+
+	return note.Bytes(), filters, nodeNames
+}
+
+// filterLogLines returns the lines of a log that contain any of the specified strings.
+func filterLogLines(logEntry []byte, filters []string) []byte {
+
+	var filteredLines bytes.Buffer
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		for _, filter := range filters {
+			if strings.Contains(line, filter) {
+				filteredLines.WriteString(line)
+				break
+			}
+		}
+	}
+
+	return filteredLines.Bytes()
+}
+
+// grepLogLines keeps the lines of a log that match the regular expression, or that do not if
+// inverted.
+func grepLogLines(logEntry []byte, regex *regexp.Regexp, invert bool) []byte {
+
+	var matchingLines bytes.Buffer
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		if line != "" && regex.MatchString(line) != invert {
+			matchingLines.WriteString(line)
+		}
+	}
+
+	return matchingLines.Bytes()
+}
+
+// filterMinLogLevel keeps the lines of a log at or above a level.  Lines without a level, such as
+// those of a stack trace, share the fate of the line before them.
+func filterMinLogLevel(logEntry []byte, minLevel string) []byte {
+
+	var filteredLines bytes.Buffer
+	include := true
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		if level := parseLogLevel(line); level != "" {
+			include = logLevelSeverity[level] >= logLevelSeverity[minLevel]
+		}
+		if include && line != "" {
+			filteredLines.WriteString(line)
+		}
+	}
+
+	return filteredLines.Bytes()
+}
+
+// getClusterState collects any requested Kubernetes objects relevant to Trident.
+func getClusterState() {
+	if storageClassDetail {
+		writeClusterState(logNameStorageClasses, getStorageClassDetail)
+	}
+	if collectEvents {
+		writeClusterState(logNameEvents, getEvents)
+	}
+	if tlsInfo {
+		writeClusterState(logNameTLSInfo, getTLSInfo)
+	}
+	if tridentConfig || configDiffBaseline != "" {
+		writeClusterState(logNameTridentConfig, getTridentConfig)
+	}
+	if configDiffBaseline != "" {
+		writeClusterState(logNameConfigDiff, getConfigDiff)
+	}
+	if tridentCRs {
+		for _, crdName := range tridentCRCRDs {
+			crdName := crdName
+			logName := "crs/" + strings.Split(crdName, ".")[0] + ".yaml"
+			writeClusterState(logName, func() ([]byte, error) { return getTridentCRs(crdName) })
+		}
+	}
+	if k8sResources {
+		getKubernetesResources()
+	}
+	if effectiveConfig {
+		writeClusterState(logNameEffectiveConfig, getEffectiveConfig)
+	}
+}
+
+// tridentCRCRDs are the CRDs of the Trident custom resources collected with --crs.
+var tridentCRCRDs = []string{BackendCRDName, VolumeCRDName, NodeCRDName, SnapshotCRDName, TransactionCRDName}
+
+// getTridentCRs returns the custom resources of a Trident CRD in the Trident namespace, as YAML.
+// A CRD that is not installed, as in an older Trident, yields a note rather than an error.
+func getTridentCRs(crdName string) ([]byte, error) {
+
+	crsCommand := []string{"get", crdName, "-n", TridentPodNamespace, "-o=yaml"}
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(crsCommand...)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "the server doesn't have a resource type") {
+			return []byte(fmt.Sprintf("# CRD %s is not installed\n", crdName)), nil
+		}
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// writeClusterState writes the output of a cluster state collector as a log, recording any
+// failure in the log errors.
+func writeClusterState(logName string, collector func() ([]byte, error)) {
+
+	stateBytes, err := collector()
+	if err != nil {
+		logErrors.add(fmt.Sprintf("could not collect %s; %v", logName, err))
+		return
+	}
+
+	if err = writeLogEntry(logName, stateBytes, archiveEntryFlags{}); err != nil {
+		writeError := fmt.Sprintf("could not write log %s; %v", logName, err)
+		logErrors.add(writeError)
+	}
+}
+
+// storageClassSettings are the settings of a storage class that affect Trident volumes.
+type storageClassSettings struct {
+	Name                 string                             `json:"name"`
+	Provisioner          string                             `json:"provisioner"`
+	Parameters           map[string]string                  `json:"parameters,omitempty"`
+	ReclaimPolicy        *k8s.PersistentVolumeReclaimPolicy `json:"reclaimPolicy,omitempty"`
+	VolumeBindingMode    *storagev1.VolumeBindingMode       `json:"volumeBindingMode,omitempty"`
+	AllowVolumeExpansion *bool                              `json:"allowVolumeExpansion,omitempty"`
+	MountOptions         []string                           `json:"mountOptions,omitempty"`
+	AllowedTopologies    []k8s.TopologySelectorTerm         `json:"allowedTopologies,omitempty"`
+}
+
+// getStorageClassDetail returns the Trident CSIDriver and the settings of every storage class
+// provisioned by Trident, as YAML.
+func getStorageClassDetail() ([]byte, error) {
+
+	var detail bytes.Buffer
+
+	detail.WriteString("# CSIDriver\n")
+	var csiDriver storagev1beta1.CSIDriver
+	if err := getKubernetesObjects(&csiDriver, "get", "csidriver", frontendcsi.Provisioner, "-o=json"); err != nil {
+		fmt.Fprintf(&detail, "# Could not get CSIDriver %s; %v\n", frontendcsi.Provisioner, err)
+	} else {
+		driverYAML, err := yaml.Marshal(struct {
+			Name string                       `json:"name"`
+			Spec storagev1beta1.CSIDriverSpec `json:"spec"`
+		}{csiDriver.Name, csiDriver.Spec})
+		if err != nil {
+			return nil, err
+		}
+		detail.Write(driverYAML)
+	}
+
+	var storageClasses storagev1.StorageClassList
+	if err := getKubernetesObjects(&storageClasses, "get", "storageclass", "-o=json"); err != nil {
+		return nil, err
+	}
+
+	settingsYAML, err := yaml.Marshal(tridentStorageClassSettings(storageClasses.Items))
+	if err != nil {
+		return nil, err
+	}
+	detail.WriteString("---\n# StorageClasses\n")
+	detail.Write(settingsYAML)
+
+	return detail.Bytes(), nil
+}
+
+// tridentStorageClassSettings returns the settings of the storage classes provisioned by Trident.
+func tridentStorageClassSettings(storageClasses []storagev1.StorageClass) []storageClassSettings {
+
+	settings := make([]storageClassSettings, 0)
+	for _, sc := range storageClasses {
+		if sc.Provisioner != frontendcsi.Provisioner && sc.Provisioner != frontendcsi.LegacyProvisioner {
+			continue
+		}
+		settings = append(settings, storageClassSettings{
+			Name:                 sc.Name,
+			Provisioner:          sc.Provisioner,
+			Parameters:           sc.Parameters,
+			ReclaimPolicy:        sc.ReclaimPolicy,
+			VolumeBindingMode:    sc.VolumeBindingMode,
+			AllowVolumeExpansion: sc.AllowVolumeExpansion,
+			MountOptions:         sc.MountOptions,
+			AllowedTopologies:    sc.AllowedTopologies,
+		})
+	}
+
+	return settings
+}
+
+// tridentConfigCRDs are the Trident custom resources that hold configuration rather than state.
+var tridentConfigCRDs = []string{BackendCRDName, StorageClassCRDName, VersionCRDName}
+
+// getTridentConfig returns the Trident configuration custom resources and the settings of the
+// storage classes provisioned by Trident as a single YAML document, keyed by resource and name.
+// Secrets are redacted as in the effective configuration.
+// Object metadata is omitted so the document differs only when the configuration does.
+func getTridentConfig() ([]byte, error) {
+
+	tridentConfig := make(map[string]map[string]interface{})
+
+	for _, crdName := range tridentConfigCRDs {
+		var list struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := getKubernetesObjects(&list, "get", crdName, "-n", TridentPodNamespace, "-o=json"); err != nil {
+			return nil, fmt.Errorf("could not get %s; %v", crdName, err)
+		}
+
+		resources := make(map[string]interface{}, len(list.Items))
+		for _, item := range list.Items {
+			var name string
+			if metadata, ok := item["metadata"].(map[string]interface{}); ok {
+				name, _ = metadata["name"].(string)
+			}
+			delete(item, "metadata")
+			delete(item, "apiVersion")
+			delete(item, "kind")
+			redactConfigSecrets(item)
+			resources[name] = item
+		}
+		tridentConfig[strings.Split(crdName, ".")[0]] = resources
+	}
+
+	var storageClasses storagev1.StorageClassList
+	if err := getKubernetesObjects(&storageClasses, "get", "storageclass", "-o=json"); err != nil {
+		return nil, fmt.Errorf("could not get storage classes; %v", err)
+	}
+	settings := make(map[string]interface{})
+	for _, sc := range tridentStorageClassSettings(storageClasses.Items) {
+		for parameter := range sc.Parameters {
+			if isConfigSecretKey(parameter) {
+				sc.Parameters[parameter] = redactedValue
+			}
+		}
+		settings[sc.Name] = sc
+	}
+	tridentConfig["storageclasses"] = settings
+
+	return yaml.Marshal(tridentConfig)
+}
+
+// getConfigDiff returns a unified diff of the Trident configuration in the baseline archive
+// against the current configuration, both with their secrets redacted.
+func getConfigDiff() ([]byte, error) {
+
+	baselineConfig, err := readArchiveFile(configDiffBaseline, logNameTridentConfig)
+	if err != nil {
+		return nil, err
+	}
+	// A baseline collected before its secrets were redacted must not leak them into the diff
+	if baselineConfig, err = redactConfigYAML(baselineConfig); err != nil {
+		return nil, fmt.Errorf("could not parse %s in %s; %v", logNameTridentConfig, configDiffBaseline, err)
+	}
+
+	currentConfig, err := getTridentConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return diffTridentConfig(configDiffBaseline, baselineConfig, currentConfig)
+}
+
+// diffTridentConfig returns a unified diff of two Trident configuration documents, or a note
+// if they are the same.
+func diffTridentConfig(baselineName string, baselineConfig, currentConfig []byte) ([]byte, error) {
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(baselineConfig)),
+		B:        difflib.SplitLines(string(currentConfig)),
+		FromFile: baselineName,
+		ToFile:   "current",
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if diff == "" {
+		return []byte(fmt.Sprintf("No Trident configuration changes since %s.\n", baselineName)), nil
+	}
+	return []byte(diff), nil
+}
+
+// readArchiveFile returns the content of the named entry in a support archive.
+func readArchiveFile(archiveName, entryName string) ([]byte, error) {
+
+	files, err := readArchiveEntries(archiveName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.name == entryName {
+			return file.content, nil
+		}
+	}
+
+	return nil, fmt.Errorf("archive %s does not contain %s; collect it with --trident-config", archiveName,
+		entryName)
+}
+
+// versionInfo records the versions of tridentctl and the running Trident controller.
+type versionInfo struct {
+	Client          api.Version  `json:"client"`
+	Server          *api.Version `json:"server,omitempty"`
+	ServerError     string       `json:"serverError,omitempty"`
+	ControllerImage string       `json:"controllerImage,omitempty"`
+}
+
+// getVersionInfo returns the client and server versions, as reported by 'tridentctl version',
+// along with the image of the running Trident controller.  The client version is always
+// available, so failures to get the others are recorded rather than returned.
+func getVersionInfo() ([]byte, error) {
+
+	info := versionInfo{Client: getClientVersion().Client}
+
+	var serverVersion rest.GetVersionResponse
+	var err error
+	if OperatingMode == ModeTunnel {
+		// A failure to get the server version should not fail the collection
+		savedExitCode := ExitCode
+		serverVersion, err = getVersionFromTunnel()
+		ExitCode = savedExitCode
+	} else {
+		serverVersion, err = getVersionFromRest()
+	}
+	if err == nil {
+		var parsedServerVersion *utils.Version
+		if parsedServerVersion, err = utils.ParseDate(serverVersion.Version); err == nil {
+			info.Server = &addClientVersion(parsedServerVersion).Server
+		}
+	}
+	if err != nil {
+		info.ServerError = err.Error()
+	}
+
+	if OperatingMode == ModeTunnel {
+		var pod k8s.Pod
+		if err := getKubernetesObjects(&pod, "get", "pod", TridentPodName, "-n", TridentPodNamespace, "-o=json"); err == nil {
+			for _, container := range pod.Spec.Containers {
+				if container.Name == config.ContainerTrident {
+					info.ControllerImage = container.Image
+				}
+			}
+		}
+	}
+	archiveVersion = &info
+
+	return yaml.Marshal(info)
+}
+
+// getEvents returns the events in the Trident namespace, oldest first.
+func getEvents() ([]byte, error) {
+
+	eventsCommand := []string{"get", "events", "-n", TridentPodNamespace, "--sort-by=.lastTimestamp"}
+	printInvokedCommand(KubernetesCLI, eventsCommand)
+
+	return cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(eventsCommand...)...)
+}
+
+// getTLSInfo describes the certificates used by the Trident controller's HTTPS REST interface,
+// read from the controller container.  Private keys are never read.
+func getTLSInfo() ([]byte, error) {
+
+	var pod k8s.Pod
+	if err := getKubernetesObjects(&pod, "get", "pod", TridentPodName, "-n", TridentPodNamespace, "-o=json"); err != nil {
+		return nil, fmt.Errorf("could not get pod %s; %v", TridentPodName, err)
+	}
+
+	var containerArgs []string
+	for _, container := range pod.Spec.Containers {
+		if container.Name == config.ContainerTrident {
+			containerArgs = append(container.Command, container.Args...)
+		}
+	}
+	tridentFlags := parseContainerFlags(containerArgs, "https_rest")
+
+	if enabled, _ := strconv.ParseBool(tridentFlags["https_rest"]); !enabled {
+		return []byte("TLS is not in use; the Trident REST interface is served only over HTTP.\n"), nil
+	}
+
+	certificates := []struct{ name, flag, defaultPath string }{
+		{"Server certificate", "https_server_cert", config.ServerCertPath},
+		{"CA certificate", "https_ca_cert", config.CACertPath},
+	}
+
+	var info bytes.Buffer
+	for _, certificate := range certificates {
+		certPath, ok := tridentFlags[certificate.flag]
+		if !ok {
+			certPath = certificate.defaultPath
+		}
+		if certPath == "" {
+			fmt.Fprintf(&info, "%s: not configured\n\n", certificate.name)
+			continue
+		}
+
+		fmt.Fprintf(&info, "%s: %s\n", certificate.name, certPath)
+		catCommand := []string{"exec", TridentPodName, "-n", TridentPodNamespace, "-c", config.ContainerTrident,
+			"--", "cat", certPath}
+		certPEM, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(catCommand...)...)
+		if err != nil {
+			fmt.Fprintf(&info, "  could not read certificate; %v\n\n", err)
+			continue
+		}
+		info.Write(describeCertificates(certPEM, time.Now()))
+		info.WriteString("\n")
+	}
+
+	return info.Bytes(), nil
+}
+
+// parseContainerFlags returns the values of the Go-style flags in a container command line,
+// keyed by flag name.  Boolean flags, which take no separate value, must be named.
+func parseContainerFlags(args []string, boolFlags ...string) map[string]string {
+
+	isBool := make(map[string]bool, len(boolFlags))
+	for _, boolFlag := range boolFlags {
+		isBool[boolFlag] = true
+	}
+
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name := strings.TrimLeft(args[i], "-")
+		if index := strings.Index(name, "="); index >= 0 {
+			flags[name[:index]] = name[index+1:]
+		} else if isBool[name] {
+			flags[name] = "true"
+		} else if i+1 < len(args) {
+			i++
+			flags[name] = args[i]
+		}
+	}
+
+	return flags
+}
+
+// describeCertificates returns the subject, issuer, names, and validity of each certificate in a
+// PEM file.  Any private key in the file is skipped.
+func describeCertificates(certPEM []byte, now time.Time) []byte {
+
+	var description bytes.Buffer
+
+	found := false
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		found = true
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			fmt.Fprintf(&description, "  could not parse certificate; %v\n", err)
+			continue
+		}
+
+		names := append([]string{}, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			names = append(names, ip.String())
+		}
+
+		validity := fmt.Sprintf("expires in %v", cert.NotAfter.Sub(now).Round(time.Hour))
+		if now.After(cert.NotAfter) {
+			validity = "EXPIRED"
+		} else if now.Before(cert.NotBefore) {
+			validity = "NOT YET VALID"
+		}
+
+		fmt.Fprintf(&description, "  subject: %s\n", cert.Subject)
+		fmt.Fprintf(&description, "  issuer: %s\n", cert.Issuer)
+		fmt.Fprintf(&description, "  names: %s\n", strings.Join(names, ", "))
+		fmt.Fprintf(&description, "  serial: %s\n", cert.SerialNumber)
+		fmt.Fprintf(&description, "  notBefore: %s\n", cert.NotBefore.UTC().Format(time.RFC3339))
+		fmt.Fprintf(&description, "  notAfter: %s (%s)\n", cert.NotAfter.UTC().Format(time.RFC3339), validity)
+	}
+
+	if !found {
+		description.WriteString("  no certificate found\n")
+	}
+
+	return description.Bytes()
+}
+
+// storageArray identifies a storage array and the strings that mark log lines relevant to it:
+// the names and addresses of its backends and the names of their volumes.
+type storageArray struct {
+	identity string
+	tokens   []string
+}
+
+// writeArrayLogs writes, for each storage array used by a Trident backend, the lines of each
+// collected container log that are relevant to it.  Logs with no relevant lines are omitted, as
+// are arrays with no relevant logs.
+func writeArrayLogs() {
+
+	arrays, err := getStorageArrays()
+	if err != nil {
+		logErrors.add(fmt.Sprintf("could not correlate storage arrays; %v", err))
+		return
+	}
+
+	logNames := make([]string, 0, len(arrayLogSources))
+	for logName := range arrayLogSources {
+		logNames = append(logNames, logName)
+	}
+	sort.Strings(logNames)
+
+	for _, array := range arrays {
+		for _, logName := range logNames {
+			arrayLog := filterLogLines(arrayLogSources[logName], array.tokens)
+			if len(arrayLog) == 0 {
+				continue
+			}
+			arrayLogName := maskString("arrays/" + array.identity + "/" + logName)
+			flags := archiveEntryFlags{Filtered: true, LineCount: countLines(arrayLog)}
+			if err := writeTransformedLogEntry(arrayLogName, arrayLog, flags); err != nil {
+				writeError := fmt.Sprintf("could not write log %s; %v", arrayLogName, err)
+				logErrors.add(writeError)
+			}
+		}
+	}
+}
+
+// getStorageArrays returns the storage arrays used by the Trident backends.
+func getStorageArrays() ([]storageArray, error) {
+
+	var backends tridentv1.TridentBackendList
+	if err := getKubernetesObjects(&backends, "get", BackendCRDName, "-n", TridentPodNamespace, "-o=json"); err != nil {
+		return nil, fmt.Errorf("could not get backends; %v", err)
+	}
+
+	var volumes tridentv1.TridentVolumeList
+	if err := getKubernetesObjects(&volumes, "get", VolumeCRDName, "-n", TridentPodNamespace, "-o=json"); err != nil {
+		return nil, fmt.Errorf("could not get volumes; %v", err)
+	}
+
+	return correlateStorageArrays(backends.Items, volumes.Items), nil
+}
+
+// arrayIdentityFields are the backend config fields that identify a storage array, in order of
+// preference.  Some are URLs, of which only the host is used.
+var arrayIdentityFields = []string{"managementLIF", "EndPoint", "webProxyHostname", "controllerA", "apiURL"}
+
+// arrayTokenFields are the backend config fields whose values may appear in relevant log lines.
+var arrayTokenFields = []string{"managementLIF", "dataLIF", "svm", "SVIP", "webProxyHostname", "controllerA",
+	"controllerB", "hostDataIP"}
+
+var invalidArrayIdentityRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// correlateStorageArrays groups the Trident backends by the storage array they use, collecting
+// the names and addresses of each array's backends and the names of their volumes.
+func correlateStorageArrays(
+	backends []*tridentv1.TridentBackend, volumes []*tridentv1.TridentVolume,
+) []storageArray {
+
+	tokensByArray := make(map[string]map[string]bool)
+	arrayByBackendUUID := make(map[string]string)
+
+	for _, backend := range backends {
+		// The config holds one driver-specific config, keyed by driver
+		var driverConfigs map[string]map[string]interface{}
+		if err := json.Unmarshal(backend.Config.Raw, &driverConfigs); err != nil {
+			continue
+		}
+		for _, driverConfig := range driverConfigs {
+			identity := ""
+			for _, field := range arrayIdentityFields {
+				if value, _ := driverConfig[field].(string); value != "" {
+					identity = arrayHost(value)
+					break
+				}
+			}
+			if identity == "" {
+				identity = backend.BackendName
+			}
+			identity = invalidArrayIdentityRegex.ReplaceAllString(identity, "_")
+
+			if tokensByArray[identity] == nil {
+				tokensByArray[identity] = make(map[string]bool)
+			}
+			tokensByArray[identity][backend.BackendName] = true
+			for _, field := range arrayTokenFields {
+				if value, _ := driverConfig[field].(string); value != "" {
+					tokensByArray[identity][arrayHost(value)] = true
+				}
+			}
+			arrayByBackendUUID[backend.BackendUUID] = identity
+		}
+	}
+
+	for _, volume := range volumes {
+		identity, ok := arrayByBackendUUID[volume.BackendUUID]
+		if !ok {
+			continue
+		}
+		tokensByArray[identity][volume.Name] = true
+		var volumeConfig struct {
+			InternalName string `json:"internalName"`
+		}
+		if err := json.Unmarshal(volume.Config.Raw, &volumeConfig); err == nil && volumeConfig.InternalName != "" {
+			tokensByArray[identity][volumeConfig.InternalName] = true
+		}
+	}
+
+	arrays := make([]storageArray, 0, len(tokensByArray))
+	for identity, tokenSet := range tokensByArray {
+		tokens := make([]string, 0, len(tokenSet))
+		for token := range tokenSet {
+			if token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+		sort.Strings(tokens)
+		arrays = append(arrays, storageArray{identity: identity, tokens: tokens})
+	}
+	sort.Slice(arrays, func(i, j int) bool { return arrays[i].identity < arrays[j].identity })
+
+	return arrays
+}
+
+// arrayHost returns the host of a URL, dropping any credentials it contains, or of a host and
+// port, or else the value itself.
+func arrayHost(value string) string {
+	if parsedURL, err := url.Parse(value); err == nil && parsedURL.Host != "" {
+		return parsedURL.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		return host
+	}
+	return value
+}
+
+// checkValidLogScope maps the --controller-only and --node-only shortcuts onto the log type and
+// --nodes-only, checking that they do not contradict each other or an explicit log type.
+func checkValidLogScope() error {
+
+	if controllerOnly && (nodeOnly || nodesOnly) {
+		return errors.New("--controller-only cannot be used with --node-only or --nodes-only")
+	}
+
+	if nodeOnly {
+		nodesOnly = true
+	}
+
+	if controllerOnly {
+		if logType != logTypeAuto && logType != logTypeTrident {
+			return fmt.Errorf("--controller-only cannot be used with --log %s", logType)
+		}
+		if len(nodes) > 0 || nodePattern != "" {
+			return errors.New("--controller-only cannot be used with --node or --node-pattern")
+		}
+		logType = logTypeTrident
+	}
+
+	return nil
+}
+
+// getNodesOnlyLogs collects the logs from the selected Trident node pods, skipping the controller.
+func getNodesOnlyLogs() error {
+
+	// Fail if no node pods could be selected, since the collection would otherwise be empty
+	if err := getSelectedNodeLogs(logNameNode); err != nil {
+		return err
+	}
+
+	if previous {
+		getSelectedNodeLogs(logNameNodePrevious)
+	}
+
+	return nil
+}
+
+// getContainerRuntimeLogs collects the logs of a Trident container running outside Kubernetes
+// using the CLI of its container runtime.
+func getContainerRuntimeLogs() error {
+
+	if follow {
+		return errors.New("--follow requires a Trident running in Kubernetes")
+	}
+
+	runtimeCLI, err := discoverContainerRuntime()
+	if err != nil {
+		return err
+	}
+
+	logsCommand := []string{"logs", runtimeContainer}
+	if tailLines >= 0 {
+		logsCommand = append(logsCommand, fmt.Sprintf("--tail=%d", tailLines))
+	}
+	if since != "" {
+		logsCommand = append(logsCommand, "--since="+since)
+	}
+	if !logSinceTime.IsZero() {
+		logsCommand = append(logsCommand, "--since="+logSinceTime.Format(time.RFC3339))
+	}
+	if !logUntilTime.IsZero() {
+		logsCommand = append(logsCommand, "--until="+logUntilTime.Format(time.RFC3339))
+	}
+	if timestamps {
+		logsCommand = append(logsCommand, "--timestamps")
+	}
+
+	printInvokedCommand(runtimeCLI, logsCommand)
+
+	result := collectionResult{Name: logNameTrident, Container: runtimeContainer}
+
+	logBytes, err := exec.Command(runtimeCLI, logsCommand...).CombinedOutput()
+	if err != nil {
+		logErrors.add(string(logBytes))
+		result.Error = commandErrorMessage(logBytes, err)
+		collectionResults = append(collectionResults, result)
+		return err
+	}
+
+	result.Bytes = len(logBytes)
+	if err = writeLogs(logNameTrident, logBytes); err != nil {
+		writeError := fmt.Sprintf("could not write log %s; %v", logNameTrident, err)
+		logErrors.add(writeError)
+		result.Error = writeError
+	}
+	collectionResults = append(collectionResults, result)
+
+	if apiAccess {
+		writeAPIAccessLog(logNameTrident, logBytes)
+	}
+
+	return nil
+}
+
+// discoverContainerRuntime returns the CLI of the container runtime specified with --runtime,
+// or else the first of docker or podman that responds.
+func discoverContainerRuntime() (string, error) {
+
+	switch containerRuntime {
+	case runtimeDocker, runtimePodman:
+		return containerRuntime, nil
+	case "":
+	default:
+		return "", fmt.Errorf("%s is not a supported container runtime", containerRuntime)
+	}
+
+	for _, runtimeCLI := range []string{runtimeDocker, runtimePodman} {
+		_, err := exec.Command(runtimeCLI, "version").Output()
+		if GetExitCodeFromError(err) == ExitCodeSuccess {
+			return runtimeCLI, nil
+		}
+	}
+
+	return "", errors.New("could not determine the container runtime running Trident; " +
+		"use --runtime to specify docker or podman")
+}
+
+// checkValidLogTimes validates the options limiting the age of the collected log entries.  It
+// runs before pod discovery, so a bad value is reported without delay.
+func checkValidLogTimes() error {
+
+	if since != "" && sinceTime != "" {
+		return errors.New("--since and --since-time cannot be used together")
+	}
+	if (since != "" || sinceTime != "") && (aroundTime != "" || fromEvents) {
+		return errors.New("--since and --since-time cannot be used with --around or --from-events")
+	}
+
+	if since != "" {
+		sinceDuration, err := time.ParseDuration(since)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid --since duration; %v", since, err)
+		}
+		if sinceDuration <= 0 {
+			return fmt.Errorf("the --since duration must be positive, not %v", sinceDuration)
+		}
+	}
+
+	if sinceTime != "" {
+		parsedTime, err := time.Parse(time.RFC3339, sinceTime)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid RFC3339 --since-time; %v", sinceTime, err)
+		}
+		logSinceTime = parsedTime
+	}
+
+	return nil
+}
+
+func checkValidLog() error {
+	switch logType {
+	case logTypeTrident, logTypeAuto, logTypeAll:
+	default:
+		return fmt.Errorf("%s is not a valid Trident log", logType)
+	}
+
+	if err := checkValidSidecars(); err != nil {
+		return err
+	}
+
+	if logsContainer != "" && logsContainer != config.ContainerTrident {
+		if sidecars {
+			return errors.New("--container and --sidecars cannot be used together")
+		}
+		if apiAccess || autoExpand {
+			return fmt.Errorf("--api-access and --auto-expand require the %s container and cannot be used "+
+				"with --container", config.ContainerTrident)
+		}
+	}
+
+	if len(nodes) > 0 && nodeSelector != "" {
+		return errors.New("--node and --selector cannot be used together")
+	}
+	if len(nodes) > 0 && len(excludedNodes) > 0 {
+		return errors.New("--node and --exclude-node cannot be used together")
+	}
+	if nodePattern != "" {
+		if len(nodes) > 0 {
+			return errors.New("--node and --node-pattern cannot be used together")
+		}
+		var err error
+		if nodePatternMatcher, err = compileNodePattern(nodePattern); err != nil {
+			return err
+		}
+	}
+
+	if err := checkValidLogScope(); err != nil {
+		return err
+	}
+
+	if nodesOnly && apiAccess {
+		return errors.New("--api-access requires the Trident controller log and cannot be used with --nodes-only")
+	}
+
+	for _, mask := range masks {
+		if mask == "" {
+			return errors.New("--mask values must not be empty")
+		}
+	}
+	maskReplacer = buildMaskReplacer(masks)
+
+	if ownedBy != "" {
+		if _, _, err := parseOwnedBy(ownedBy); err != nil {
+			return err
+		}
+	}
+
+	if goroutineID < 0 {
+		return fmt.Errorf("%d is not a valid goroutine ID", goroutineID)
+	}
+
+	if configDiffBaseline != "" {
+		if _, err := os.Stat(configDiffBaseline); err != nil {
+			return fmt.Errorf("could not find the --config-diff baseline archive; %v", err)
+		}
+	}
+
+	if redactionReport && !redact {
+		return errors.New("--redaction-report requires --redact")
+	}
+
+	if autoExpand && autoExpandMax < 1 {
+		return fmt.Errorf("%d is not a valid --auto-expand-max limit", autoExpandMax)
+	}
+
+	if levelsOnly {
+		splitByLevel = true
+	}
+	if splitByLevel && !archive {
+		return errors.New("--split-by-level and --levels-only are only supported in archive mode")
+	}
+
+	if groupByArray && !archive {
+		return errors.New("--group-by-array is only supported in archive mode")
+	}
+
+	if OutputFormat == FormatJSON && (!archive || estimate) {
+		return errors.New("JSON output is only supported in archive mode")
+	}
+
+	if err := checkValidNDJSON(); err != nil {
+		return err
+	}
+
+	if err := readArchivePassword(); err != nil {
+		return err
+	}
+
+	if err := checkValidUpload(); err != nil {
+		return err
+	}
+
+	if err := checkValidK8sResources(); err != nil {
+		return err
+	}
+
+	if err := checkValidMerge(); err != nil {
+		return err
+	}
+
+	if err := checkValidEntryFilters(); err != nil {
+		return err
+	}
+
+	if err := checkValidAllNamespaces(); err != nil {
+		return err
+	}
+
+	if err := checkValidArchiveStdout(); err != nil {
+		return err
+	}
+
+	if err := checkValidPod(); err != nil {
+		return err
+	}
+
+	if noPrevious && previous {
+		return errors.New("--no-previous cannot be used with --previous")
+	}
+
+	if (archiveName != "" || forceArchive) && !archive {
+		return errors.New("--filename and --force are only supported in archive mode")
+	}
+
+	switch colorMode {
+	case colorAlways:
+		colorize = !archive && !estimate
+	case colorAuto:
+		// Color only a terminal, and not a pager or file that may not interpret it
+		colorize = !archive && !estimate && !pager && teeFileName == "" && os.Getenv("NO_COLOR") == "" &&
+			terminal.IsTerminal(int(os.Stdout.Fd()))
+	case colorNever:
+		colorize = false
+	default:
+		return fmt.Errorf("%s is not a valid color mode", colorMode)
+	}
+
+	if logsQuiet && logsVerbose {
+		return errors.New("--quiet and --verbose cannot be used together")
+	}
+
+	if logsOutDir != "" {
+		if archive || estimate {
+			return errors.New("--out-dir is only supported in console mode; use --output-dir for the archive")
+		}
+		if follow || pager || teeFileName != "" {
+			return errors.New("--out-dir cannot be used with --follow, --pager or --tee")
+		}
+		colorize = false
+	}
+
+	if archive && teeFileName != "" {
+		return errors.New("--tee is only supported in console mode")
+	}
+	if (archive || estimate) && pager {
+		return errors.New("--pager is only supported in console mode")
+	}
+	if follow {
+		if archive || estimate {
+			return errors.New("--follow is only supported in console mode")
+		}
+		if apiAccess || autoExpand {
+			return errors.New("--api-access and --auto-expand require complete logs and cannot be used with --follow")
+		}
+	}
+
+	if aroundTime != "" {
+		around, err := time.Parse(time.RFC3339, aroundTime)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid RFC3339 time; %v", aroundTime, err)
+		}
+		if aroundWindow <= 0 {
+			return fmt.Errorf("the --around window must be positive, not %v", aroundWindow)
+		}
+		logSinceTime = around.Add(-aroundWindow)
+		logUntilTime = around.Add(aroundWindow)
+	}
+
+	if grepPattern != "" {
+		var err error
+		if grepRegex, err = regexp.Compile(grepPattern); err != nil {
+			return fmt.Errorf("%s is not a valid --grep regular expression; %v", grepPattern, err)
+		}
+	} else if invertMatch {
+		return errors.New("--invert-match requires --grep")
+	}
+
+	if parallelism < 1 {
+		return fmt.Errorf("%d is not a valid --parallelism", parallelism)
+	}
+
+	if requestTimeout < 0 {
+		return fmt.Errorf("%v is not a valid --request-timeout duration", requestTimeout)
+	}
+
+	if logsRetries < 0 {
+		return fmt.Errorf("%d is not a valid --retry count", logsRetries)
+	}
+
+	if maxLogBytes < 0 {
+		return fmt.Errorf("%d is not a valid --max-log-bytes limit", maxLogBytes)
+	}
+
+	if tailLines < -1 {
+		return fmt.Errorf("%d is not a valid --tail line count", tailLines)
+	}
+
+	if logsRate < 0 {
+		return fmt.Errorf("%v is not a valid --rate", logsRate)
+	} else if logsRate > 0 {
+		// A burst of one spaces every request evenly, even at the start of the collection
+		logsRateLimiter = flowcontrol.NewTokenBucketRateLimiter(float32(logsRate), 1)
+	}
+
+	if err := checkValidRateLimit(); err != nil {
+		return err
+	}
+
+	if fromEvents {
+		if aroundTime != "" {
+			return errors.New("--from-events cannot be used with --around")
+		}
+		if containerRuntime != "" {
+			return errors.New("--from-events requires a Trident running in Kubernetes")
+		}
+		if err := setWindowFromEvents(); err != nil {
+			return err
+		}
+	}
+
+	if archiveFormat != archiveFormatZip && archiveFormat != archiveFormatTgz {
+		return fmt.Errorf("%s is not a valid archive format", archiveFormat)
+	}
+
+	if compressThreshold < 0 {
+		return fmt.Errorf("%d is not a valid compression threshold", compressThreshold)
+	}
+
+	if compressionLevel != flate.DefaultCompression {
+		if !archive || estimate {
+			return errors.New("--compression-level is only supported in archive mode")
+		}
+		if compressionLevel < flate.NoCompression || compressionLevel > flate.BestCompression {
+			return fmt.Errorf("%d is not a valid --compression-level; use 0 to 9", compressionLevel)
+		}
+	}
+
+	if splitSize != "" {
+		if !archive || estimate {
+			return errors.New("--split-size is only supported in archive mode")
+		}
+		size, err := humanize.ParseBytes(splitSize)
+		if err != nil || size == 0 {
+			return fmt.Errorf("%s is not a valid --split-size", splitSize)
+		}
+		archiveSplitSize = int64(size)
+	}
+
+	return nil
+}
+
+const (
+	// eventsWindowMargin is added around the span of the Warning events, since the log entries
+	// explaining an event usually precede it.
+	eventsWindowMargin = time.Minute
+
+	// defaultEventsWindow is collected when there are no Warning events to define the window.
+	defaultEventsWindow = time.Hour
+)
+
+// setWindowFromEvents limits the collected logs to the span of the Warning events in the
+// Trident namespace, or to the default window if there are none.
+func setWindowFromEvents() error {
+
+	var events k8s.EventList
+	if err := getKubernetesObjects(&events, "get", "events", "-n", TridentPodNamespace,
+		"--field-selector=type=Warning", "-o=json"); err != nil {
+		return fmt.Errorf("could not get events; %v", err)
+	}
+
+	first, last, ok := eventsSpan(events.Items)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "No Warning events found in namespace %s; collecting the last %v of logs.\n",
+			TridentPodNamespace, defaultEventsWindow)
+		logSinceTime = time.Now().Add(-defaultEventsWindow)
+		return nil
+	}
+
+	logSinceTime = first.Add(-eventsWindowMargin)
+	logUntilTime = last.Add(eventsWindowMargin)
+
+	if Debug {
+		fmt.Printf("Collecting logs from %s to %s.\n", logSinceTime.Format(time.RFC3339),
+			logUntilTime.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// eventsSpan returns the times of the first and last occurrences of a set of events.
+func eventsSpan(events []k8s.Event) (time.Time, time.Time, bool) {
+
+	var first, last time.Time
+
+	for _, event := range events {
+		firstTime, lastTime := event.FirstTimestamp.Time, event.LastTimestamp.Time
+		if firstTime.IsZero() {
+			firstTime = event.EventTime.Time
+		}
+		if lastTime.IsZero() {
+			lastTime = firstTime
+		}
+		if firstTime.IsZero() {
+			continue
+		}
+		if first.IsZero() || firstTime.Before(first) {
+			first = firstTime
+		}
+		if lastTime.After(last) {
+			last = lastTime
+		}
+	}
+
+	return first, last, !first.IsZero()
+}
+
+// buildLogsCommand returns the Kubernetes CLI arguments to get the logs of a container.
+func buildLogsCommand(pod, container string, prev bool) []string {
+
+	logsCommand := []string{"logs", pod, "-n", TridentPodNamespace, "-c", container, fmt.Sprintf("--previous=%v", prev)}
+
+	if tailLines >= 0 {
+		logsCommand = append(logsCommand, fmt.Sprintf("--tail=%d", tailLines))
+	}
+	if since != "" {
+		logsCommand = append(logsCommand, "--since="+since)
+	}
+	if follow {
+		logsCommand = append(logsCommand, "-f")
+	}
+
+	if !logSinceTime.IsZero() {
+		logsCommand = append(logsCommand, "--since-time="+logSinceTime.Format(time.RFC3339))
+	}
+	if timestamps || !logUntilTime.IsZero() {
+		// The CLI has no upper time bound, so timestamps are needed to filter the lines
+		logsCommand = append(logsCommand, "--timestamps")
+	}
+
+	return kubernetesCLIArgs(logsCommand...)
+}
+
+// getContainerLogs invokes the Kubernetes CLI to get the logs of a container.  If the command
+// fails, its output is returned along with the error.
+func getContainerLogs(pod, container string, prev bool) ([]byte, error) {
+	return retryTransientFailures(func() ([]byte, error) {
+		if logsAPIClient != nil {
+			return getAPIContainerLogs(pod, container, prev)
+		}
+		if streamer, ok := cliRunner.(commandStreamer); ok && logsByteLimiter != nil {
+			var output bytes.Buffer
+			err := streamer.Stream(throttleLogsWriter(&output), KubernetesCLI,
+				prepareLogsCommand(pod, container, prev)...)
+			return output.Bytes(), err
+		}
+		return cliRunner.Run(KubernetesCLI, prepareLogsCommand(pod, container, prev)...)
+	})
+}
+
+// transientFailureRegex matches the Kubernetes CLI errors that are likely to succeed if retried.
+var transientFailureRegex = regexp.MustCompile(`(?i)connection refused|connection reset|` +
+	`TLS handshake timeout|i/o timeout|timed out|unexpected EOF|service unavailable|` +
+	`too many requests|etcdserver: request timed out`)
+
+// retryTransientFailures makes an attempt, and then as many retries as specified with --retry
+// while the attempt fails with an error that looks transient.  The wait before each retry is twice
+// as long as the one before.
+func retryTransientFailures(attempt func() ([]byte, error)) ([]byte, error) {
+
+	backoff := retryBackoff
+	for retry := 0; ; retry++ {
+		output, err := attempt()
+		if err == nil || retry >= logsRetries || !transientFailureRegex.MatchString(err.Error()) {
+			return output, err
+		}
+		if Debug {
+			fmt.Printf("Retrying in %v after transient failure: %v\n", backoff, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// requestContext returns the context of a Kubernetes CLI command, which expires after the
+// --request-timeout duration, if set.
+func requestContext() (context.Context, context.CancelFunc) {
+	if requestTimeout > 0 {
+		return context.WithTimeout(collectionContext, requestTimeout)
+	}
+	return context.WithCancel(collectionContext)
+}
+
+// prepareLogsCommand returns the arguments of the Kubernetes CLI command that gets the logs of a
+// container, waiting first for any rate limit.
+func prepareLogsCommand(pod, container string, prev bool) []string {
+
+	logsCommand := buildLogsCommand(pod, container, prev)
+
+	if logsRateLimiter != nil {
+		logsRateLimiter.Accept()
+	}
+
+	printInvokedCommand(KubernetesCLI, logsCommand)
+
+	return logsCommand
+}
+
+// printInvokedCommand prints a command about to be run, to stdout if debugging or else to stderr
+// if verbose, so the collection can be reproduced by hand.
+func printInvokedCommand(name string, args []string) {
+	if Debug {
+		fmt.Printf("Invoking command: %s %v\n", name, strings.Join(args, " "))
+	} else if logsVerbose {
+		fmt.Fprintf(os.Stderr, "Invoking command: %s %v\n", name, strings.Join(args, " "))
+	}
+}
+
+// canStreamLogs reports whether container logs may be copied to the archive or console without
+// holding them in memory, which is not possible with any option that processes a whole log.
+func canStreamLogs() bool {
+	return !estimate && goroutineID == 0 && grepRegex == nil && logUntilTime.IsZero() &&
+		len(lineFilters) == 0 && maskReplacer == nil && !redact && !decodeBase64 && maxLogBytes == 0 &&
+		!(archive && (groupByArray || splitByLevel)) && !apiAccess && !autoExpand && OutputFormat != FormatNDJSON
+}
+
+// lineCountingWriter counts the bytes and lines written through it.
+type lineCountingWriter struct {
+	writer   io.Writer
+	size     int64
+	newlines int
+	last     byte
+}
+
+func (w *lineCountingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.size += int64(n)
+	w.newlines += bytes.Count(p[:n], []byte("\n"))
+	if n > 0 {
+		w.last = p[n-1]
+	}
+	return n, err
+}
+
+// lines returns the number of lines written, counting any final line without a newline, as
+// countLines does.
+func (w *lineCountingWriter) lines() int {
+	if w.size > 0 && w.last != '\n' {
+		return w.newlines + 1
+	}
+	return w.newlines
+}
+
+// spoolContainerLogs copies the logs of a container to a temporary file as they are read, so that
+// memory use does not grow with the size of the log, and returns the file along with a reader of
+// the logs it holds.  If the support archive is encrypted, so is the file, with a key held only in
+// memory.  The caller must close and remove the file.
+func spoolContainerLogs(pod, container string, prev bool) (*os.File, io.Reader, *lineCountingWriter, error) {
+
+	spoolFile, err := ioutil.TempFile("", "trident-log-")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not create temporary file; %v", err)
+	}
+
+	var spoolOutput io.Writer = spoolFile
+	var spoolContent io.Reader = spoolFile
+	if archive && archivePassword != "" {
+		var encryption *spoolEncryption
+		if encryption, err = newSpoolEncryption(); err != nil {
+			spoolFile.Close()
+			os.Remove(spoolFile.Name())
+			return nil, nil, nil, fmt.Errorf("could not encrypt temporary file; %v", err)
+		}
+		spoolOutput, spoolContent = encryption.writer(spoolFile), encryption.reader(spoolFile)
+	}
+
+	spoolWriter := &lineCountingWriter{writer: spoolOutput}
+	if logsAPIClient != nil {
+		err = streamAPIContainerLogs(spoolWriter, pod, container, prev)
+	} else if streamer, ok := cliRunner.(commandStreamer); ok {
+		err = streamer.Stream(throttleLogsWriter(spoolWriter), KubernetesCLI, prepareLogsCommand(pod, container, prev)...)
+	} else {
+		var output []byte
+		if output, err = cliRunner.Run(KubernetesCLI, prepareLogsCommand(pod, container, prev)...); err == nil {
+			_, err = spoolWriter.Write(output)
+		}
+	}
+	if err == nil {
+		_, err = spoolFile.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spoolFile.Close()
+		os.Remove(spoolFile.Name())
+		return nil, nil, nil, err
+	}
+
+	return spoolFile, spoolContent, spoolWriter, nil
+}
+
+// streamContainerLogs collects the logs of a container through a temporary file and copies them
+// to the archive or console, recording the outcome in the collection results, and returns their
+// size.  A failure to get an optional log is not recorded.
+func streamContainerLogs(result collectionResult, optional bool) (int, error) {
+
+	var spoolFile *os.File
+	var spoolContent io.Reader
+	var spoolWriter *lineCountingWriter
+	_, err := retryTransientFailures(func() ([]byte, error) {
+		var attemptErr error
+		spoolFile, spoolContent, spoolWriter, attemptErr = spoolContainerLogs(result.Pod, result.Container, result.Previous)
+		return nil, attemptErr
+	})
+	if spoolFile != nil {
+		defer os.Remove(spoolFile.Name())
+		defer spoolFile.Close()
+	}
+
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	if err != nil {
+		if optional {
+			return 0, err
+		}
+		logErrors.add(err.Error())
+		result.Error = err.Error()
+	} else {
+		result.Bytes = int(spoolWriter.size)
+		if err = writeStreamedLogEntry(result.Name, spoolContent, spoolWriter); err != nil {
+			writeError := fmt.Sprintf("could not write log %s; %v", result.Name, err)
+			logErrors.add(writeError)
+			result.Error = writeError
+		}
+	}
+
+	collectionResults = append(collectionResults, result)
+
+	return result.Bytes, err
+}
+
+// writeStreamedLogEntry copies a spooled container log to the archive, console or --out-dir file.
+func writeStreamedLogEntry(logName string, content io.Reader, spoolWriter *lineCountingWriter) error {
+
+	if !archiveEntrySelected(logName) {
+		return nil
+	}
+	logName = logNamespacePrefix + logName
+
+	flags := archiveEntryFlags{
+		Filtered:  !logSinceTime.IsZero(),
+		LineCount: spoolWriter.lines(),
+	}
+
+	if !archive && logsOutDir != "" {
+		logFile, err := createLogFile(logName)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+		if _, err = io.Copy(logFile, content); err != nil {
+			return err
+		}
+		if !logsQuiet {
+			fmt.Fprintf(consoleOutput, "Wrote %s log to %s.\n", logName, logFile.Name())
+		}
+		return nil
+	}
+
+	if !archive {
+		fmt.Fprintf(consoleOutput, "%s log:\n", logName)
+		if colorize {
+			var colorizer logLineColorizer
+			reader := bufio.NewReader(content)
+			for {
+				line, err := reader.ReadString('\n')
+				fmt.Fprint(consoleOutput, colorizer.colorize(line))
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return err
+				}
+			}
+		} else if _, err := io.Copy(consoleOutput, content); err != nil {
+			return err
+		}
+		fmt.Fprintf(consoleOutput, "\n")
+		return nil
+	}
+
+	digest := sha256.New()
+	compression, err := archiveWriter.WriteEntryFrom(logName, io.TeeReader(content, digest), spoolWriter.size,
+		spoolWriter.size >= int64(compressThreshold))
+	if err != nil {
+		return err
+	}
+	archiveManifest = append(archiveManifest, archiveManifestEntry{
+		Name:              logName,
+		Size:              int(spoolWriter.size),
+		SHA256:            hex.EncodeToString(digest.Sum(nil)),
+		Compression:       compression,
+		Part:              archiveVolume(),
+		archiveEntryFlags: flags,
+	})
+	printArchiveProgress("Wrote %s log to %s archive file.\n", logName, zipFileName)
+
+	return nil
+}
+
+// collectContainerLogs gets the logs of a container and writes them, recording the outcome in
+// the collection results.  Any failure is also added to the log errors.  If the log is empty,
+// as when the container just restarted, the log of its previous instance is collected too.
+func collectContainerLogs(logName, pod, container, nodeName string, prev bool) ([]byte, error) {
+
+	if follow {
+		return nil, followContainerLogs(logName, pod, container, prev)
+	}
+
+	result := collectionResult{Name: logName, Namespace: TridentPodNamespace, Pod: pod, Container: container,
+		Node: nodeName, Previous: prev}
+	logBytes, size, err := collectContainerLog(result, false)
+
+	if err == nil && size == 0 && !prev && fallbackToPrevious() {
+		result.Name, result.Previous = previousLogName(logName), true
+		_, _, _ = collectContainerLog(result, true)
+	}
+
+	return logBytes, err
+}
+
+// fallbackToPrevious reports whether the previous log of a container is collected when its
+// current log is empty.  That is the default in auto mode, unless every previous log is already
+// being collected or none are to be.
+func fallbackToPrevious() bool {
+	return logType == logTypeAuto && !noFallback && !noPrevious && !previous
+}
+
+// previousLogName returns the name of the previous log of a container from that of its current
+// log, following the names of the logs collected with --previous.
+func previousLogName(logName string) string {
+	if sidecarIndex := strings.Index(logName, "-sidecar-"); sidecarIndex >= 0 {
+		return logName[:sidecarIndex] + "-previous" + logName[sidecarIndex:]
+	}
+	return logName + "-previous"
+}
+
+// collectContainerLog gets and writes one container log, returning it unless it was streamed or
+// only estimated,
+// along with its size.  A failure to get an optional log, such as the previous log of a container
+// that has not restarted, is not recorded.
+func collectContainerLog(result collectionResult, optional bool) ([]byte, int, error) {
+
+	if estimate {
+		size, err := estimateContainerLog(result, optional)
+		return nil, size, err
+	}
+
+	if canStreamLogs() {
+		size, err := streamContainerLogs(result, optional)
+		return nil, size, err
+	}
+
+	logBytes, err := getContainerLogs(result.Pod, result.Container, result.Previous)
+
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	if err != nil {
+		if optional {
+			return nil, 0, err
+		}
+		logErrors.add(err.Error())
+		result.Error = err.Error()
+	} else {
+		result.Bytes = len(logBytes)
+		if OutputFormat == FormatNDJSON {
+			ndjsonSources[result.Name] = result
+		}
+		if err = writeLogs(result.Name, logBytes); err != nil {
+			writeError := fmt.Sprintf("could not write log %s; %v", result.Name, err)
+			logErrors.add(writeError)
+			result.Error = writeError
+		}
+	}
+
+	collectionResults = append(collectionResults, result)
+
+	return logBytes, len(logBytes), err
+}
+
+// followContainerLogs starts streaming the logs of a container to the console, one line at a
+// time, until the stream ends or the follow context is canceled.  When following more than one
+// container, each line is prefixed with its source.
+func followContainerLogs(logName, pod, container string, prev bool) error {
+
+	logsCommand := buildLogsCommand(pod, container, prev)
+
+	printInvokedCommand(KubernetesCLI, logsCommand)
+
+	followCmd := exec.CommandContext(followContext, KubernetesCLI, logsCommand...)
+	followCmd.Stderr = os.Stderr
+	stdout, err := followCmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = followCmd.Start(); err != nil {
+		return fmt.Errorf("could not follow log %s; %v", logName, err)
+	}
+
+	prefix := ""
+	if sidecars {
+		prefix = "[" + pod + "/" + container + "] "
+	} else if logType == logTypeAll {
+		prefix = "[" + pod + "] "
+	}
+
+	followWait.Add(1)
+	go func() {
+		defer followWait.Done()
+
+		var colorizer logLineColorizer
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := []byte(scanner.Text() + "\n")
+			if grepRegex != nil && len(grepLogLines(line, grepRegex, invertMatch)) == 0 {
+				continue
+			}
+			logsLock.Lock()
+			var flags archiveEntryFlags
+			_, line = transformLogEntry(logName, line, &flags)
+			if len(line) > 0 && colorize {
+				fmt.Fprintf(consoleOutput, "%s%s", prefix, colorizer.colorize(string(line)))
+			} else if len(line) > 0 {
+				fmt.Fprintf(consoleOutput, "%s%s", prefix, line)
+			}
+			logsLock.Unlock()
+		}
+
+		// A stream stopped by Ctrl-C is not a failure
+		if err := followCmd.Wait(); err != nil && followContext.Err() == nil {
+			logsLock.Lock()
+			logErrors.add(fmt.Sprintf("could not follow log %s; %v", logName, err))
+			logsLock.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// commandErrorMessage returns the output of a failed command, or the error if there was none.
+func commandErrorMessage(output []byte, err error) string {
+	if message := strings.TrimSpace(string(output)); message != "" {
+		return message
+	}
+	return err.Error()
+}
+
+// filterLogsUntil removes the log lines timestamped after the specified time, as well as the
+// timestamps themselves unless they are to be kept.  Lines without a timestamp share the fate of
+// the line before them.
+func filterLogsUntil(logEntry []byte, until time.Time, keepTimestamps bool) []byte {
+
+	var filteredLines bytes.Buffer
+	include := true
+
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		if line == "" {
+			continue
+		}
+		timestamp, message := splitLogTimestamp(line)
+		if !timestamp.IsZero() {
+			include = !timestamp.After(until)
+		}
+		if include && keepTimestamps {
+			filteredLines.WriteString(line)
+		} else if include {
+			filteredLines.WriteString(message)
+		}
+	}
+
+	return filteredLines.Bytes()
+}
+
+// buildMaskReplacer returns a replacer that substitutes a numbered token for each distinct mask
+// string, so that masked values may still be cross-referenced, or nil if there are no masks.
+func buildMaskReplacer(masks []string) *strings.Replacer {
+
+	tokens := make(map[string]string)
+	orderedMasks := make([]string, 0, len(masks))
+	for _, mask := range masks {
+		if _, ok := tokens[mask]; !ok {
+			tokens[mask] = fmt.Sprintf("***%d***", len(tokens)+1)
+			orderedMasks = append(orderedMasks, mask)
+		}
+	}
+	if len(orderedMasks) == 0 {
+		return nil
+	}
+
+	// Replace longer strings first so a mask containing another is replaced intact
+	sort.SliceStable(orderedMasks, func(i, j int) bool { return len(orderedMasks[i]) > len(orderedMasks[j]) })
+
+	replacements := make([]string, 0, 2*len(orderedMasks))
+	for _, mask := range orderedMasks {
+		replacements = append(replacements, mask, tokens[mask])
+	}
+
+	return strings.NewReplacer(replacements...)
 }
 
-func writeLogs(logName string, logEntry []byte) error {
-	if archive {
-		entry, err := zipWriter.Create(logName)
-		if err != nil {
-			return err
-		}
-		_, err = entry.Write(logEntry)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("Wrote %s log to %s archive file.\n", logName, zipFileName)
-	} else {
-		fmt.Printf("%s log:\n", logName)
-		fmt.Printf("%s\n", string(logEntry))
+// maskString replaces any masked strings in the supplied text.
+func maskString(text string) string {
+	if maskReplacer == nil {
+		return text
 	}
-	return nil
+	return maskReplacer.Replace(text)
 }
 
-func archiveLogs() error {
+// countLines returns the number of lines in a log, including any final unterminated line.
+func countLines(logEntry []byte) int {
+	lines := bytes.Count(logEntry, []byte("\n"))
+	if len(logEntry) > 0 && logEntry[len(logEntry)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
 
-	// In archive mode, "auto" means to attempt to get all logs (current & previous).
-	if logType == logTypeAuto {
-		logType = logTypeAll
-		previous = true
-		sidecars = true
+// splitLogTimestamp separates the RFC3339 timestamp the Kubernetes CLI prefixes to a log line
+// from the rest of the line.  If the line has no timestamp, a zero time and the whole line are
+// returned.
+func splitLogTimestamp(line string) (time.Time, string) {
+
+	index := strings.IndexByte(line, ' ')
+	if index < 0 {
+		return time.Time{}, line
 	}
 
-	// Create archive file.
-	zipFileName = time.Now().Format(archiveFilenameFormat)
-	zipFile, err := os.Create(zipFileName)
+	timestamp, err := time.Parse(time.RFC3339Nano, line[:index])
+	if err != nil {
+		return time.Time{}, line
+	}
+
+	return timestamp, line[index+1:]
+}
+
+func getTridentLogs(logName string) error {
+
+	var container string
+	var prev bool
+
+	switch logName {
+	case logNameTrident:
+		container, prev = config.ContainerTrident, false
+	case logNameTridentPrevious:
+		container, prev = config.ContainerTrident, true
+	default:
+		return fmt.Errorf("%s is not a valid Trident log", logName)
+	}
+
+	container, err := selectPodContainer(TridentPodName, container)
 	if err != nil {
 		return err
 	}
-	defer zipFile.Close()
 
-	zipWriter = zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+	// Get logs
+	logBytes, err := collectContainerLogs(containerLogName(logName, container), TridentPodName, container, "", prev)
+	if err == nil && apiAccess {
+		writeAPIAccessLog(logName, logBytes)
+	}
+	if err == nil && autoExpand && !prev {
+		controllerLog = logBytes
+	}
 
-	getLogs()
+	if startupArgs && !prev {
+		writePodStartupArgs(logNameControllerArgs, TridentPodName)
+	}
 
-	if len(logErrors) > 0 {
-		entry, err := zipWriter.Create("errors")
+	if sidecars {
+		var tridentSidecars []string
+		tridentSidecars, err = listTridentSidecars(TridentPodName, TridentPodNamespace)
 		if err != nil {
-			return err
+			return fmt.Errorf("error listing trident sidecar containers; %v", err)
 		}
-		_, err = entry.Write(logErrors)
-		if err != nil {
-			return err
+		for _, sidecar := range selectSidecars(tridentSidecars) {
+			// Get logs
+			_, err = collectContainerLogs(logName+"-sidecar-"+sidecar, TridentPodName, sidecar, "", prev)
+		}
+		if initErr := collectInitContainerLogs(logName, TridentPodName, "", prev); initErr != nil {
+			return initErr
 		}
-		fmt.Printf("Wrote %s log to %s archive file.\n", "errors", zipFileName)
 	}
 
-	return nil
+	return err
 }
 
-func consoleLogs() error {
+// collectInitContainerLogs gets the logs of the init containers of a Trident pod, such as those
+// that configure a node host.
+func collectInitContainerLogs(logName, pod, nodeName string, prev bool) error {
 
-	err := getLogs()
+	// Only the sidecars named with --sidecar are collected
+	if len(selectedSidecars) > 0 {
+		return nil
+	}
 
-	SetExitCodeFromError(err)
+	initContainers, err := listTridentInitContainers(pod, TridentPodNamespace)
 	if err != nil {
-		// Preserve anything written to stdout/stderr
-		logMessage := strings.TrimSuffix(strings.TrimSpace(string(logErrors)), ".")
-		if len(logMessage) > 0 {
-			errMessage := strings.TrimSuffix(strings.TrimSpace(err.Error()), ".")
-			return fmt.Errorf("%s. %s", errMessage, logMessage)
-		} else {
-			return err
-		}
+		return fmt.Errorf("error listing trident init containers; %v", err)
+	}
+	for _, initContainer := range initContainers {
+		// Get logs
+		collectContainerLogs(logName+"-init-"+initContainer, pod, initContainer, nodeName, prev)
 	}
 	return nil
 }
 
-func getLogs() error {
+// selectPodContainer returns the container specified with --container, after checking that the
+// pod has it, or the default container if none was specified.
+func selectPodContainer(podName, defaultContainer string) (string, error) {
 
-	var err error
+	if logsContainer == "" {
+		return defaultContainer, nil
+	}
 
-	if OperatingMode != ModeTunnel {
-		return errors.New("'tridentctl logs' only supports Trident running in a Kubernetes pod")
+	var pod k8s.Pod
+	if err := getKubernetesObjects(&pod, "get", "pod", podName, "-n", TridentPodNamespace, "-o=json"); err != nil {
+		return "", fmt.Errorf("could not get pod %s; %v", podName, err)
 	}
 
-	switch logType {
-	case logTypeTrident, logTypeAuto:
-		if node == "" {
-			err = getTridentLogs(logNameTrident)
-		} else {
-			err = getNodeLogs(logNameNode, node)
-		}
-	case logTypeAll:
-		getTridentLogs(logNameTrident)
-		if node == "" {
-			getAllNodeLogs(logNameNode)
-		} else {
-			getNodeLogs(logNameNode, node)
+	containerNames := make([]string, 0, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		if container.Name == logsContainer {
+			return logsContainer, nil
 		}
+		containerNames = append(containerNames, container.Name)
 	}
 
-	if previous {
-		switch logType {
-		case logTypeTrident, logTypeAuto:
-			if node == "" {
-				getTridentLogs(logNameTridentPrevious)
-			} else {
-				getNodeLogs(logNameNodePrevious, node)
-			}
-		case logTypeAll:
-			getTridentLogs(logNameTridentPrevious)
-			if node == "" {
-				getAllNodeLogs(logNameNodePrevious)
-			} else {
-				getNodeLogs(logNameNodePrevious, node)
-			}
-		}
+	return "", fmt.Errorf("pod %s has no container %s; available containers: %s", podName, logsContainer,
+		strings.Join(containerNames, ", "))
+}
+
+// containerLogName returns the name of the log of a container in a Trident pod, which names any
+// container other than the main Trident container as a sidecar.
+func containerLogName(logName, container string) string {
+	if container == config.ContainerTrident {
+		return logName
+	}
+	return logName + "-sidecar-" + container
+}
+
+// writePodStartupArgs writes the command and arguments of each container in a pod as a log.
+func writePodStartupArgs(logName, podName string) {
+
+	var pod k8s.Pod
+	err := getKubernetesObjects(&pod, "get", "pod", podName, "-n", TridentPodNamespace, "-o=json")
+
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	if err != nil {
+		getError := fmt.Sprintf("could not get pod %s; %v", podName, err)
+		logErrors.add(getError)
+		return
 	}
 
-	return err
+	if err := writeLogEntry(logName, formatStartupArgs(pod.Spec.Containers), archiveEntryFlags{}); err != nil {
+		writeError := fmt.Sprintf("could not write log %s; %v", logName, err)
+		logErrors.add(writeError)
+	}
 }
 
-func checkValidLog() error {
-	switch logType {
-	case logTypeTrident, logTypeAuto, logTypeAll:
-		return nil
-	default:
-		return fmt.Errorf("%s is not a valid Trident log", logType)
+// formatStartupArgs describes the command and arguments of each container.
+func formatStartupArgs(containers []k8s.Container) []byte {
+
+	var startupArgs bytes.Buffer
+	for _, container := range containers {
+		fmt.Fprintf(&startupArgs, "container: %s\n", container.Name)
+		fmt.Fprintf(&startupArgs, "  image: %s\n", container.Image)
+		fmt.Fprintf(&startupArgs, "  command: %s\n", strings.Join(container.Command, " "))
+		fmt.Fprintf(&startupArgs, "  args: %s\n", strings.Join(container.Args, " "))
 	}
+
+	return startupArgs.Bytes()
 }
 
-func getTridentLogs(logName string) error {
+var (
+	logrusLevelRegex = regexp.MustCompile(`(?:^|\s)level=(\w+)`)
+	jsonLevelRegex   = regexp.MustCompile(`"level":"(\w+)"`)
+	klogLevelRegex   = regexp.MustCompile(`^([IWEF])\d{4} `)
+	plainLevelRegex  = regexp.MustCompile(`^\[?(ERROR|WARN|WARNING|INFO|DEBUG)\b`)
+)
 
-	var container string
-	var prev bool
+// parseLogLevel returns the level of a log line in logfmt, JSON, or klog format, or starting with
+// a level token such as ERROR, normalized to one of debug, info, warning, error, fatal, or panic,
+// or an empty string if it has no level.
+func parseLogLevel(line string) string {
 
-	switch logName {
-	case logNameTrident:
-		container, prev = config.ContainerTrident, false
-	case logNameTridentPrevious:
-		container, prev = config.ContainerTrident, true
+	_, message := splitLogTimestamp(line)
+
+	var level string
+	if match := logrusLevelRegex.FindStringSubmatch(message); match != nil {
+		level = match[1]
+	} else if match = jsonLevelRegex.FindStringSubmatch(message); match != nil {
+		level = match[1]
+	} else if match = klogLevelRegex.FindStringSubmatch(message); match != nil {
+		level = map[string]string{"I": "info", "W": "warning", "E": "error", "F": "fatal"}[match[1]]
+	} else if match = plainLevelRegex.FindStringSubmatch(message); match != nil {
+		level = match[1]
+	}
+
+	switch level = strings.ToLower(level); level {
+	case "warn":
+		return "warning"
+	case "trace":
+		return "debug"
+	case "debug", "info", "warning", "error", "fatal", "panic":
+		return level
 	default:
-		return fmt.Errorf("%s is not a valid Trident log", logName)
+		return ""
 	}
+}
 
-	// Build command to get K8S logs
-	prevArg := fmt.Sprintf("--previous=%v", prev)
-	logsCommand := []string{"logs", TridentPodName, "-n", TridentPodNamespace, "-c", container, prevArg}
+// levelLogName returns the aggregated level log to which a line with the specified level belongs.
+func levelLogName(level string) string {
+	switch level {
+	case "error", "fatal", "panic":
+		return levelLogErrors
+	case "warning":
+		return levelLogWarnings
+	case "info", "debug":
+		return levelLogInfo
+	default:
+		return levelLogOther
+	}
+}
 
-	if Debug {
-		fmt.Printf("Invoking command: %s %v\n", KubernetesCLI, strings.Join(logsCommand, " "))
+// addToLevelLogs appends each line of a log, prefixed with the log name, to the aggregated
+// log for its level.
+func addToLevelLogs(logName string, logEntry []byte) {
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		if line == "" {
+			continue
+		}
+		name := levelLogName(parseLogLevel(line))
+		if levelLogs[name] == nil {
+			levelLogs[name] = &bytes.Buffer{}
+		}
+		fmt.Fprintf(levelLogs[name], "[%s] %s", logName, line)
+		if !strings.HasSuffix(line, "\n") {
+			levelLogs[name].WriteString("\n")
+		}
 	}
+}
 
-	// Get logs
-	logBytes, err := exec.Command(KubernetesCLI, logsCommand...).CombinedOutput()
-	if err != nil {
-		logErrors = appendError(logErrors, logBytes)
-	} else {
-		if err = writeLogs(logName, logBytes); err != nil {
-			writeError := fmt.Sprintf("could not write log %s; %v", logName, err)
-			logErrors = appendError(logErrors, []byte(writeError))
+// writeLevelLogs writes the aggregated level logs to the archive.
+func writeLevelLogs() {
+	for _, name := range []string{levelLogErrors, levelLogWarnings, levelLogInfo, levelLogOther} {
+		levelLog, ok := levelLogs[name]
+		if !ok {
+			continue
+		}
+		flags := archiveEntryFlags{Filtered: true, LineCount: countLines(levelLog.Bytes())}
+		if err := writeTransformedLogEntry(name, levelLog.Bytes(), flags); err != nil {
+			writeError := fmt.Sprintf("could not write log %s; %v", name, err)
+			logErrors.add(writeError)
 		}
 	}
+}
 
-	if sidecars {
-		var tridentSidecars []string
-		tridentSidecars, err = listTridentSidecars(TridentPodName, TridentPodNamespace)
-		if err != nil {
-			return fmt.Errorf("error listing trident sidecar containers; %v", err)
+// writeAPIAccessLog extracts the REST API access lines from a Trident controller log and writes
+// them as a separate log.
+func writeAPIAccessLog(logName string, logEntry []byte) {
+
+	accessLogName := logNameAPIAccess
+	if logName == logNameTridentPrevious {
+		accessLogName = logNameAPIAccessPrevious
+	}
+
+	accessLines := extractAPIAccessLines(logEntry, apiErrorsOnly)
+	if err := writeLogEntry(accessLogName, accessLines, archiveEntryFlags{Filtered: true}); err != nil {
+		writeError := fmt.Sprintf("could not write log %s; %v", accessLogName, err)
+		logErrors.add(writeError)
+	}
+}
+
+// extractAPIAccessLines returns the lines of a Trident controller log that record a completed
+// REST API call.  If errorsOnly is set, only calls with a non-2xx status code are returned.
+func extractAPIAccessLines(logEntry []byte, errorsOnly bool) []byte {
+
+	var accessLines bytes.Buffer
+
+	for _, line := range strings.Split(string(logEntry), "\n") {
+
+		// Avoid parsing lines that cannot match
+		if !strings.Contains(line, restCallCompleteMessage) {
+			continue
 		}
-		for _, sidecar := range tridentSidecars {
-			logsCommand = []string{"logs", TridentPodName, "-n", TridentPodNamespace, "-c", sidecar, prevArg}
 
-			if Debug {
-				fmt.Printf("Invoking command: %s %v\n", KubernetesCLI, strings.Join(logsCommand, " "))
-			}
+		_, message := splitLogTimestamp(line)
+		fields := parseLogLineFields(message)
+		if fields["msg"] != restCallCompleteMessage {
+			continue
+		}
 
-			// Get logs
-			logBytes, err = exec.Command(KubernetesCLI, logsCommand...).CombinedOutput()
-			if err != nil {
-				logErrors = appendError(logErrors, logBytes)
-			} else {
-				if err = writeLogs(logName+"-sidecar-"+sidecar, logBytes); err != nil {
-					writeError := fmt.Sprintf("could not write log %s; %v", logName+"-sidecar-"+sidecar, err)
-					logErrors = appendError(logErrors, []byte(writeError))
-				}
+		if errorsOnly {
+			status, err := strconv.Atoi(fields["status"])
+			if err != nil || (status >= 200 && status < 300) {
+				continue
 			}
 		}
+
+		accessLines.WriteString(line)
+		accessLines.WriteString("\n")
 	}
 
-	return err
+	return accessLines.Bytes()
+}
+
+// parseLogLineFields returns the fields of a single Trident log line, which may be formatted
+// as either logfmt text or JSON.
+func parseLogLineFields(line string) map[string]string {
+
+	trimmedLine := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmedLine, "{") {
+		return parseLogFmtMessage(trimmedLine)
+	}
+
+	var jsonFields map[string]interface{}
+	if err := json.Unmarshal([]byte(trimmedLine), &jsonFields); err != nil {
+		return parseLogFmtMessage(trimmedLine)
+	}
+
+	fields := make(map[string]string, len(jsonFields))
+	for key, value := range jsonFields {
+		fields[key] = fmt.Sprintf("%v", value)
+	}
+	return fields
 }
 
 func getNodeLogs(logName, nodeName string) error {
@@ -282,25 +3982,15 @@ func getNodeLogs(logName, nodeName string) error {
 	nodeLogName := "trident-node-" + nodeName
 	if prev == true {
 		nodeLogName = nodeLogName + "-previous"
+	} else if startupArgs {
+		writePodStartupArgs("node-args-"+nodeName+".txt", pod)
 	}
-	// Build command to get K8S logs
-	prevArg := fmt.Sprintf("--previous=%v", prev)
-	logsCommand := []string{"logs", pod, "-n", TridentPodNamespace, "-c", container, prevArg}
-
-	if Debug {
-		fmt.Printf("Invoking command: %s %v\n", KubernetesCLI, strings.Join(logsCommand, " "))
+	if container, err = selectPodContainer(pod, container); err != nil {
+		return err
 	}
 
 	// Get logs
-	logBytes, err := exec.Command(KubernetesCLI, logsCommand...).CombinedOutput()
-	if err != nil {
-		logErrors = appendError(logErrors, logBytes)
-	} else {
-		if err = writeLogs(nodeLogName, logBytes); err != nil {
-			writeError := fmt.Sprintf("could not write log %s; %v", nodeLogName, err)
-			logErrors = appendError(logErrors, []byte(writeError))
-		}
-	}
+	collectNodeLogs(containerLogName(nodeLogName, container), pod, container, nodeName, prev)
 
 	if sidecars {
 		var tridentSidecars []string
@@ -308,27 +3998,128 @@ func getNodeLogs(logName, nodeName string) error {
 		if err != nil {
 			return fmt.Errorf("error listing trident sidecar containers; %v", err)
 		}
-		for _, sidecar := range tridentSidecars {
-			logsCommand = []string{"logs", pod, "-n", TridentPodNamespace, "-c", sidecar, prevArg}
+		for _, sidecar := range selectSidecars(tridentSidecars) {
+			// Get logs
+			collectContainerLogs(nodeLogName+"-sidecar-"+sidecar, pod, sidecar, nodeName, prev)
+		}
+		if err = collectInitContainerLogs(nodeLogName, pod, nodeName, prev); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+// getSelectedNodeLogs collects the logs from the node pods specified with --node, or from all
+// node pods if none were specified.
+func getSelectedNodeLogs(logName string) error {
+
+	if len(nodes) == 0 {
+		err := getAllNodeLogs(logName)
+		if mergeErr := writeMergedNodeLog(logName); mergeErr != nil && err == nil {
+			err = mergeErr
+		}
+		return err
+	}
+
+	// Collect from every node that can be found before reporting those that could not
+	var failedNodes []string
+	for _, nodeName := range nodes {
+		if err := getNodeLogs(logName, nodeName); err != nil {
+			failedNodes = append(failedNodes, fmt.Sprintf("%s (%v)", nodeName, err))
+		}
+	}
+	if err := writeMergedNodeLog(logName); err != nil {
+		return err
+	}
+	if len(failedNodes) > 0 {
+		return fmt.Errorf("could not collect logs from node(s) %s", strings.Join(failedNodes, ", "))
+	}
+	return nil
+}
+
+// splitNodeNames splits any comma-separated values of --node into node names, dropping empty and
+// duplicate names while keeping the order in which they were given.
+func splitNodeNames(values []string) []string {
 
-			if Debug {
-				fmt.Printf("Invoking command: %s %v\n", KubernetesCLI, strings.Join(logsCommand, " "))
+	nodeNames := make([]string, 0, len(values))
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, nodeName := range strings.Split(value, ",") {
+			nodeName = strings.TrimSpace(nodeName)
+			if nodeName == "" || seen[nodeName] {
+				continue
 			}
+			seen[nodeName] = true
+			nodeNames = append(nodeNames, nodeName)
+		}
+	}
+	return nodeNames
+}
 
-			// Get logs
-			logBytes, err = exec.Command(KubernetesCLI, logsCommand...).CombinedOutput()
-			if err != nil {
-				logErrors = appendError(logErrors, logBytes)
-			} else {
-				if err = writeLogs(nodeLogName+"-sidecar-"+sidecar, logBytes); err != nil {
-					writeError := fmt.Sprintf("could not write log %s; %v", nodeLogName+"-sidecar-"+sidecar, err)
-					logErrors = appendError(logErrors, []byte(writeError))
-				}
+// nodePatternMatcher reports whether a node name matches --node-pattern, if specified.
+var nodePatternMatcher func(nodeName string) bool
+
+// compileNodePattern returns a matcher for a node name pattern.  A pattern using only the shell
+// glob wildcards must match the whole name, while one using any other regular expression syntax
+// is a regular expression matched anywhere in the name.
+func compileNodePattern(pattern string) (func(string) bool, error) {
+
+	if !strings.ContainsAny(pattern, `^$+(){}|\`) {
+		if _, err := path.Match(pattern, ""); err == nil {
+			return func(nodeName string) bool {
+				matched, _ := path.Match(pattern, nodeName)
+				return matched
+			}, nil
+		}
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid --node-pattern glob or regular expression; %v", pattern, err)
+	}
+	return regex.MatchString, nil
+}
+
+// listSelectedTridentNodes returns the Trident node pods on the nodes matching --selector and
+// --node-pattern, less those excluded with --exclude-node, keyed by node name.  The excluded
+// nodes that have a Trident node pod are recorded as skipped.
+func listSelectedTridentNodes() (map[string]string, error) {
+
+	tridentNodes, err := listTridentNodes(TridentPodNamespace, nodeSelector)
+	if err != nil {
+		return tridentNodes, err
+	}
+
+	if nodePatternMatcher != nil {
+		matchedNodes := make(map[string]string)
+		for nodeName, pod := range tridentNodes {
+			if nodePatternMatcher(nodeName) {
+				matchedNodes[nodeName] = pod
 			}
 		}
+		if len(matchedNodes) < 1 {
+			return matchedNodes, fmt.Errorf("could not find any Trident node pods on nodes matching pattern %s",
+				nodePattern)
+		}
+		tridentNodes = matchedNodes
+	}
 
+	if len(excludedNodes) > 0 {
+		skippedNodes = nil
+		for _, nodeName := range excludedNodes {
+			if _, ok := tridentNodes[nodeName]; ok {
+				delete(tridentNodes, nodeName)
+				skippedNodes = append(skippedNodes, nodeName)
+			}
+		}
+		sort.Strings(skippedNodes)
+		if len(tridentNodes) < 1 {
+			return tridentNodes, errors.New("every selected Trident node pod was excluded with --exclude-node")
+		}
 	}
-	return nil
+
+	return tridentNodes, nil
 }
 
 func getAllNodeLogs(logName string) error {
@@ -345,74 +4136,114 @@ func getAllNodeLogs(logName string) error {
 		return fmt.Errorf("%s is not a valid Trident node log", logName)
 	}
 
-	tridentNodeNames, err := listTridentNodes(TridentPodNamespace)
+	tridentNodeNames, err := listSelectedTridentNodes()
 	if err != nil {
 		return fmt.Errorf("error listing trident node pods; %v", err)
 	}
+	if len(tridentNodeNames) == 0 {
+		return errors.New("could not find any selected Trident node pods")
+	}
 
-	for node, pod := range tridentNodeNames {
-		nodeLogName := "trident-node-" + node
-		if prev == true {
-			nodeLogName = nodeLogName + "-previous"
-		}
-		// Build command to get K8S logs
-		prevArg := fmt.Sprintf("--previous=%v", prev)
-		logsCommand := []string{"logs", pod, "-n", TridentPodNamespace, "-c", container, prevArg}
+	// Collect from a bounded number of nodes at once, starting them in name order
+	nodeNames := make([]string, 0, len(tridentNodeNames))
+	for node := range tridentNodeNames {
+		nodeNames = append(nodeNames, node)
+	}
+	sort.Strings(nodeNames)
 
-		if Debug {
-			fmt.Printf("Invoking command: %s %v\n", KubernetesCLI, strings.Join(logsCommand, " "))
-		}
+	var wg sync.WaitGroup
+	// The sidecar error of each node, so that the one reported does not depend on timing
+	sidecarErrs := make([]error, len(nodeNames))
+	workers := make(chan struct{}, parallelism)
 
-		// Get logs
-		logBytes, err := exec.Command(KubernetesCLI, logsCommand...).CombinedOutput()
-		if err != nil {
-			logErrors = appendError(logErrors, logBytes)
-		} else {
-			if err = writeLogs(nodeLogName, logBytes); err != nil {
-				writeError := fmt.Sprintf("could not write log %s; %v", nodeLogName, err)
-				logErrors = appendError(logErrors, []byte(writeError))
-			}
-		}
+	for index, node := range nodeNames {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(index int, node, pod string) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
 
-		if sidecars {
-			var tridentSidecars []string
-			tridentSidecars, err = listTridentSidecars(pod, TridentPodNamespace)
+			nodeLogName := "trident-node-" + node
+			if prev == true {
+				nodeLogName = nodeLogName + "-previous"
+			} else if startupArgs {
+				writePodStartupArgs("node-args-"+node+".txt", pod)
+			}
+			podContainer, err := selectPodContainer(pod, container)
 			if err != nil {
-				return fmt.Errorf("error listing trident sidecar containers; %v", err)
+				logsLock.Lock()
+				logErrors.add(err.Error())
+				logsLock.Unlock()
+				return
 			}
-			for _, sidecar := range tridentSidecars {
-				logsCommand = []string{"logs", pod, "-n", TridentPodNamespace, "-c", sidecar, prevArg}
 
-				if Debug {
-					fmt.Printf("Invoking command: %s %v\n", KubernetesCLI, strings.Join(logsCommand, " "))
-				}
+			// Get logs
+			collectNodeLogs(containerLogName(nodeLogName, podContainer), pod, podContainer, node, prev)
 
-				// Get logs
-				logBytes, err = exec.Command(KubernetesCLI, logsCommand...).CombinedOutput()
+			if sidecars {
+				tridentSidecars, err := listTridentSidecars(pod, TridentPodNamespace)
 				if err != nil {
-					logErrors = appendError(logErrors, logBytes)
-				} else {
-					if err = writeLogs(nodeLogName+"-sidecar-"+sidecar, logBytes); err != nil {
-						writeError := fmt.Sprintf("could not write log %s; %v", nodeLogName+"-sidecar-"+sidecar, err)
-						logErrors = appendError(logErrors, []byte(writeError))
-					}
+					sidecarErrs[index] = fmt.Errorf("error listing trident sidecar containers; %v", err)
+					return
+				}
+				for _, sidecar := range selectSidecars(tridentSidecars) {
+					// Get logs
+					collectContainerLogs(nodeLogName+"-sidecar-"+sidecar, pod, sidecar, node, prev)
 				}
+				sidecarErrs[index] = collectInitContainerLogs(nodeLogName, pod, node, prev)
 			}
+		}(index, node, tridentNodeNames[node])
+	}
+	wg.Wait()
+
+	for _, sidecarErr := range sidecarErrs {
+		if sidecarErr != nil {
+			return sidecarErr
 		}
 	}
 	return nil
 }
 
-func appendError(oldErrors, newError []byte) []byte {
+// logErrorList records the errors of a collection in the order they first occurred.  An error
+// already recorded is counted rather than repeated, as in "permission denied (x3)", so that
+// errors shared by many nodes stay readable.
+type logErrorList struct {
+	messages []string
+	counts   map[string]int
+}
 
-	if len(oldErrors) == 0 {
-		return newError
-	} else {
-		oldErrorsStr := string(oldErrors)
-		oldErrorsStr = strings.TrimSpace(oldErrorsStr)
-		oldErrorsStr = strings.TrimSuffix(oldErrorsStr, ".")
-		oldErrorsStr += ". "
-		oldErrors = append([]byte(oldErrorsStr), newError...)
-		return oldErrors
+// add records an error, ignoring any surrounding space and final period.
+func (l *logErrorList) add(err string) {
+
+	message := strings.TrimSuffix(strings.TrimSpace(err), ".")
+	if message == "" {
+		return
+	}
+	if l.counts == nil {
+		l.counts = make(map[string]int)
+	}
+	if l.counts[message] == 0 {
+		l.messages = append(l.messages, message)
+	}
+	l.counts[message]++
+}
+
+// empty reports whether no error was recorded.
+func (l *logErrorList) empty() bool {
+	return len(l.messages) == 0
+}
+
+// String returns the errors as sentences, with the count of any repeated error.
+func (l *logErrorList) String() string {
+
+	sentences := make([]string, 0, len(l.messages))
+	for _, message := range l.messages {
+		if count := l.counts[message]; count > 1 {
+			message += fmt.Sprintf(" (x%d)", count)
+		}
+		sentences = append(sentences, message)
 	}
+	return strings.Join(sentences, ". ")
 }
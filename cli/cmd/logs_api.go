@@ -0,0 +1,134 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	k8s "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	useLogsAPI bool
+	// The client used to get container logs from the Kubernetes API, if --use-api was specified
+	// and credentials were found
+	logsAPIClient kubernetes.Interface
+)
+
+func init() {
+	logsCmd.Flags().BoolVar(&useLogsAPI, "use-api", false, "Get the container logs from the Kubernetes API rather than the Kubernetes CLI, if there are credentials for it.")
+}
+
+// initLogsAPIClient creates the Kubernetes API client used to get container logs, from the
+// in-cluster credentials when running in a pod, or else the kubeconfig file and context.
+// Without either, the Kubernetes CLI is used instead.
+func initLogsAPIClient() {
+
+	// There is no kubeconfig to load in a pod, so go straight to its service account
+	var restConfig *rest.Config
+	var err error
+	if InCluster {
+		restConfig, err = rest.InClusterConfig()
+	} else {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = KubernetesConfig
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: KubernetesContext}
+		restConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules,
+			overrides).ClientConfig()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not find Kubernetes API credentials, so the Kubernetes CLI is used "+
+			"instead; %v\n", err)
+		return
+	}
+
+	if logsAPIClient, err = kubernetes.NewForConfig(restConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not create a Kubernetes API client, so the Kubernetes CLI is used "+
+			"instead; %v\n", err)
+	}
+}
+
+// buildPodLogOptions returns the Kubernetes API options that get the same log lines as the
+// Kubernetes CLI command built by buildLogsCommand.
+func buildPodLogOptions(container string, prev bool) *k8s.PodLogOptions {
+
+	options := &k8s.PodLogOptions{Container: container, Previous: prev}
+
+	if tailLines >= 0 {
+		lines := tailLines
+		options.TailLines = &lines
+	}
+	if since != "" {
+		if sinceDuration, err := time.ParseDuration(since); err == nil {
+			seconds := int64(sinceDuration.Seconds())
+			options.SinceSeconds = &seconds
+		}
+	}
+	if !logSinceTime.IsZero() {
+		options.SinceTime = &metav1.Time{Time: logSinceTime}
+	}
+	// The API has no upper time bound either, so timestamps are needed to filter the lines
+	options.Timestamps = timestamps || !logUntilTime.IsZero()
+
+	return options
+}
+
+// buildLogsRequest returns the Kubernetes API request for the logs of a container.
+func buildLogsRequest(pod, container string, prev bool) *rest.Request {
+	return logsAPIClient.CoreV1().Pods(TridentPodNamespace).GetLogs(pod, buildPodLogOptions(container, prev))
+}
+
+// printInvokedRequest prints a Kubernetes API request about to be made, like printInvokedCommand,
+// so that the pod, container, and options of each log fetched are shown with either path.
+func printInvokedRequest(request *rest.Request) {
+	if Debug {
+		fmt.Printf("Invoking request: GET %s\n", request.URL())
+	} else if logsVerbose {
+		fmt.Fprintf(os.Stderr, "Invoking request: GET %s\n", request.URL())
+	}
+}
+
+// streamAPIContainerLogs copies the logs of a container from the Kubernetes API as they are read.
+func streamAPIContainerLogs(w io.Writer, pod, container string, prev bool) error {
+
+	if logsRateLimiter != nil {
+		logsRateLimiter.Accept()
+	}
+
+	ctx, cancel := requestContext()
+	defer cancel()
+
+	request := buildLogsRequest(pod, container, prev)
+	printInvokedRequest(request)
+
+	stream, err := request.Context(ctx).Stream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if _, err = io.Copy(w, throttleLogsReader(stream)); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %v getting the logs of container %s in pod %s",
+				requestTimeout, container, pod)
+		}
+		return err
+	}
+	return nil
+}
+
+// getAPIContainerLogs returns the logs of a container from the Kubernetes API.
+func getAPIContainerLogs(pod, container string, prev bool) ([]byte, error) {
+	var logs bytes.Buffer
+	err := streamAPIContainerLogs(&logs, pod, container, prev)
+	return logs.Bytes(), err
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8s "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestBuildPodLogOptions(t *testing.T) {
+
+	savedTail, savedSince, savedSinceTime, savedTimestamps := tailLines, since, logSinceTime, timestamps
+	defer func() {
+		tailLines, since, logSinceTime, timestamps = savedTail, savedSince, savedSinceTime, savedTimestamps
+	}()
+
+	tailLines, since, logSinceTime, timestamps = -1, "", time.Time{}, false
+	assert.Equal(t, &k8s.PodLogOptions{Container: "trident-main"}, buildPodLogOptions("trident-main", false))
+
+	tail, seconds := int64(100), int64(1800)
+	tailLines, since = 100, "30m"
+	assert.Equal(t, &k8s.PodLogOptions{Container: "trident-main", Previous: true, TailLines: &tail,
+		SinceSeconds: &seconds}, buildPodLogOptions("trident-main", true))
+
+	sinceMoment := time.Date(2020, 1, 20, 15, 4, 5, 0, time.UTC)
+	tailLines, since, logSinceTime, timestamps = -1, "", sinceMoment, true
+	assert.Equal(t, &k8s.PodLogOptions{Container: "csi-provisioner", SinceTime: &metav1.Time{Time: sinceMoment},
+		Timestamps: true}, buildPodLogOptions("csi-provisioner", false))
+}
+
+func TestInitLogsAPIClientKubeConfig(t *testing.T) {
+
+	savedConfig, savedContext, savedInCluster := KubernetesConfig, KubernetesContext, InCluster
+	defer func() {
+		KubernetesConfig, KubernetesContext, InCluster = savedConfig, savedContext, savedInCluster
+		logsAPIClient = nil
+	}()
+
+	kubeConfigFile, err := ioutil.TempFile("", "kubeconfig-")
+	assert.Nil(t, err)
+	defer os.Remove(kubeConfigFile.Name())
+	_, err = kubeConfigFile.WriteString(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: abc
+`)
+	assert.Nil(t, err)
+	assert.Nil(t, kubeConfigFile.Close())
+
+	KubernetesConfig, KubernetesContext, InCluster, logsAPIClient = kubeConfigFile.Name(), "", false, nil
+	initLogsAPIClient()
+	assert.NotNil(t, logsAPIClient)
+
+	// A kubeconfig that cannot be loaded must not fall back to any in-cluster credentials
+	KubernetesContext, logsAPIClient = "missing", nil
+	initLogsAPIClient()
+	assert.Nil(t, logsAPIClient)
+}
+
+func TestBuildLogsRequest(t *testing.T) {
+
+	savedClient, savedNamespace, savedTail := logsAPIClient, TridentPodNamespace, tailLines
+	defer func() { logsAPIClient, TridentPodNamespace, tailLines = savedClient, savedNamespace, savedTail }()
+
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: "https://127.0.0.1:6443"})
+	assert.Nil(t, err)
+	logsAPIClient, TridentPodNamespace, tailLines = client, "trident", 100
+
+	assert.Equal(t, "https://127.0.0.1:6443/api/v1/namespaces/trident/pods/trident-csi-a/log?"+
+		"container=trident-main&previous=true&tailLines=100",
+		buildLogsRequest("trident-csi-a", "trident-main", true).URL().String())
+}
@@ -0,0 +1,270 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	archiveFormatZip = "zip"
+	archiveFormatTgz = "tgz"
+)
+
+// supportArchiveWriter writes the entries of a support archive in one archive format.
+type supportArchiveWriter interface {
+	// WriteEntry adds an entry to the archive, compressing it if requested and supported by the
+	// format, and returns the compression method used.
+	WriteEntry(name string, content []byte, compress bool) (string, error)
+	// WriteEntryFrom adds an entry of the specified size to the archive, copying its content from
+	// the reader, so the content need not be held in memory.
+	WriteEntryFrom(name string, content io.Reader, size int64, compress bool) (string, error)
+	// Close finishes the archive without closing the underlying writer.
+	Close() error
+}
+
+// newArchiveWriter returns a support archive writer for the specified format.
+func newArchiveWriter(format string, w io.Writer) (supportArchiveWriter, error) {
+	switch format {
+	case archiveFormatZip:
+		zipWriter := zip.NewWriter(w)
+		if compressionLevel != flate.DefaultCompression {
+			zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(out, compressionLevel)
+			})
+		}
+		return &zipArchiveWriter{writer: zipWriter}, nil
+	case archiveFormatTgz:
+		gzipWriter, err := gzip.NewWriterLevel(w, compressionLevel)
+		if err != nil {
+			return nil, err
+		}
+		return &tgzArchiveWriter{gzipWriter: gzipWriter, tarWriter: tar.NewWriter(gzipWriter)}, nil
+	default:
+		return nil, fmt.Errorf("%s is not a valid archive format", format)
+	}
+}
+
+// closeSupportArchive finishes a support archive and then closes its output, if any, returning
+// the first error.  An encrypted archive cannot be decrypted until its final block is written
+// when the output is closed.
+func closeSupportArchive(writer supportArchiveWriter, closeOutput func() error) error {
+	err := writer.Close()
+	if closeOutput != nil {
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// archiveExtension returns the file name extension for an archive format.
+func archiveExtension(format string) string {
+	if format == archiveFormatTgz {
+		return ".tar.gz"
+	}
+	return ".zip"
+}
+
+type zipArchiveWriter struct {
+	writer *zip.Writer
+}
+
+func (z *zipArchiveWriter) WriteEntry(name string, content []byte, compress bool) (string, error) {
+	return z.WriteEntryFrom(name, bytes.NewReader(content), int64(len(content)), compress)
+}
+
+func (z *zipArchiveWriter) WriteEntryFrom(name string, content io.Reader, _ int64, compress bool) (string, error) {
+
+	// At level 0, an entry is stored rather than wrapped in uncompressed deflate blocks
+	header := &zip.FileHeader{Name: name, Method: zip.Store}
+	compression := "store"
+	if compress && compressionLevel != flate.NoCompression {
+		header.Method = zip.Deflate
+		compression = "deflate"
+	}
+
+	entry, err := z.writer.CreateHeader(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(entry, content); err != nil {
+		return "", err
+	}
+
+	return compression, nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (z *zipArchiveWriter) Flush() error {
+	return z.writer.Flush()
+}
+
+func (z *zipArchiveWriter) Close() error {
+	return z.writer.Close()
+}
+
+// tgzArchiveWriter writes a gzipped tar archive, which compresses the whole archive rather than
+// single entries.
+type tgzArchiveWriter struct {
+	gzipWriter *gzip.Writer
+	tarWriter  *tar.Writer
+}
+
+func (t *tgzArchiveWriter) WriteEntry(name string, content []byte, compress bool) (string, error) {
+	return t.WriteEntryFrom(name, bytes.NewReader(content), int64(len(content)), compress)
+}
+
+func (t *tgzArchiveWriter) WriteEntryFrom(name string, content io.Reader, size int64, _ bool) (string, error) {
+
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := t.tarWriter.WriteHeader(header); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(t.tarWriter, content); err != nil {
+		return "", err
+	}
+
+	return "gzip", nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (t *tgzArchiveWriter) Flush() error {
+	if err := t.tarWriter.Flush(); err != nil {
+		return err
+	}
+	return t.gzipWriter.Flush()
+}
+
+func (t *tgzArchiveWriter) Close() error {
+	if err := t.tarWriter.Close(); err != nil {
+		return err
+	}
+	return t.gzipWriter.Close()
+}
+
+// splitArchiveWriter writes a support archive as a series of numbered volumes, each a complete
+// archive in its own right, starting the next volume once the current one reaches the size
+// limit.  An entry is never split across volumes, so a volume may exceed the limit by up to one
+// entry.
+type splitArchiveWriter struct {
+	format string
+	limit  int64
+	// openVolume creates the file of a volume, returning its writer and a function closing it
+	openVolume func(volume int) (io.Writer, func() error, error)
+
+	volume      int
+	entries     int
+	written     *countingWriter
+	writer      supportArchiveWriter
+	closeVolume func() error
+}
+
+// newSplitArchiveWriter returns a split support archive writer, having started its first volume.
+func newSplitArchiveWriter(
+	format string, limit int64, openVolume func(volume int) (io.Writer, func() error, error),
+) (*splitArchiveWriter, error) {
+	s := &splitArchiveWriter{format: format, limit: limit, openVolume: openVolume}
+	if err := s.nextVolume(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *splitArchiveWriter) WriteEntry(name string, content []byte, compress bool) (string, error) {
+	return s.WriteEntryFrom(name, bytes.NewReader(content), int64(len(content)), compress)
+}
+
+func (s *splitArchiveWriter) WriteEntryFrom(name string, content io.Reader, size int64, compress bool) (string, error) {
+
+	if s.entries > 0 && s.written.count >= s.limit {
+		if err := s.nextVolume(); err != nil {
+			return "", err
+		}
+	}
+
+	s.entries++
+	compression, err := s.writer.WriteEntryFrom(name, content, size, compress)
+	if err != nil {
+		return "", err
+	}
+
+	// The archive writers buffer their output, which must be flushed to measure the volume
+	if flusher, ok := s.writer.(interface{ Flush() error }); ok {
+		if err = flusher.Flush(); err != nil {
+			return "", err
+		}
+	}
+	return compression, nil
+}
+
+// Volume returns the number of the volume to which entries are being written.
+func (s *splitArchiveWriter) Volume() int {
+	return s.volume
+}
+
+// nextVolume finishes the current volume, if any, and starts the next one.
+func (s *splitArchiveWriter) nextVolume() error {
+
+	if err := s.finishVolume(); err != nil {
+		return err
+	}
+
+	volumeOutput, closeVolume, err := s.openVolume(s.volume + 1)
+	if err != nil {
+		return err
+	}
+	s.written = &countingWriter{writer: volumeOutput}
+	if s.writer, err = newArchiveWriter(s.format, s.written); err != nil {
+		_ = closeVolume()
+		return err
+	}
+	s.volume++
+	s.entries = 0
+	s.closeVolume = closeVolume
+
+	return nil
+}
+
+func (s *splitArchiveWriter) finishVolume() error {
+
+	if s.writer == nil {
+		return nil
+	}
+	err := s.writer.Close()
+	if closeErr := s.closeVolume(); err == nil {
+		err = closeErr
+	}
+	s.writer = nil
+	return err
+}
+
+// Close finishes the last volume and closes its file.
+func (s *splitArchiveWriter) Close() error {
+	return s.finishVolume()
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.count += int64(n)
+	return n, err
+}
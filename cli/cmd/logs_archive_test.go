@@ -0,0 +1,190 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveWriters(t *testing.T) {
+
+	readers := map[string]func([]byte) ([]archiveFile, error){
+		archiveFormatZip: readZipEntries,
+		archiveFormatTgz: readTarGzEntries,
+	}
+	compressions := map[string][]string{
+		archiveFormatZip: {"store", "deflate"},
+		archiveFormatTgz: {"gzip", "gzip"},
+	}
+
+	for format, readEntries := range readers {
+		var buffer bytes.Buffer
+		writer, err := newArchiveWriter(format, &buffer)
+		assert.Nil(t, err)
+
+		compression, err := writer.WriteEntry("trident-controller", []byte("line 1\n"), false)
+		assert.Nil(t, err)
+		assert.Equal(t, compressions[format][0], compression)
+
+		compression, err = writer.WriteEntry("arrays/10.0.0.1/trident-node-a", []byte("line 2\n"), true)
+		assert.Nil(t, err)
+		assert.Equal(t, compressions[format][1], compression)
+		assert.Nil(t, writer.Close())
+
+		files, err := readEntries(buffer.Bytes())
+		assert.Nil(t, err, format)
+		assert.Equal(t, []archiveFile{
+			{name: "trident-controller", content: []byte("line 1\n")},
+			{name: "arrays/10.0.0.1/trident-node-a", content: []byte("line 2\n")},
+		}, files, format)
+	}
+
+	_, err := newArchiveWriter("rar", &bytes.Buffer{})
+	assert.NotNil(t, err)
+	assert.Equal(t, ".zip", archiveExtension(archiveFormatZip))
+	assert.Equal(t, ".tar.gz", archiveExtension(archiveFormatTgz))
+}
+
+func TestCloseSupportArchive(t *testing.T) {
+
+	var buffer bytes.Buffer
+	writer, err := newArchiveWriter(archiveFormatZip, &buffer)
+	assert.Nil(t, err)
+	_, err = writer.WriteEntry("trident", []byte("line 1\n"), false)
+	assert.Nil(t, err)
+
+	// The output is closed even if finishing the archive fails, and the first error is returned
+	var closed []string
+	assert.EqualError(t, closeSupportArchive(writer, func() error {
+		closed = append(closed, "output")
+		return errors.New("disk full")
+	}), "disk full")
+	assert.Equal(t, []string{"output"}, closed)
+
+	files, err := readZipEntries(buffer.Bytes())
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	assert.Nil(t, closeSupportArchive(&zipArchiveWriter{writer: zip.NewWriter(&bytes.Buffer{})}, nil))
+}
+
+func TestArchiveCompressionLevel(t *testing.T) {
+
+	defer func() { compressionLevel = flate.DefaultCompression }()
+
+	readers := map[string]func([]byte) ([]archiveFile, error){
+		archiveFormatZip: readZipEntries,
+		archiveFormatTgz: readTarGzEntries,
+	}
+	content := bytes.Repeat([]byte("level=info msg=\"Volume published.\"\n"), 1000)
+	sizes := make(map[int]int)
+
+	for _, level := range []int{flate.NoCompression, flate.BestSpeed, flate.BestCompression} {
+		compressionLevel = level
+		for format, readEntries := range readers {
+			var buffer bytes.Buffer
+			writer, err := newArchiveWriter(format, &buffer)
+			assert.Nil(t, err)
+
+			compression, err := writer.WriteEntry("trident-controller", content, true)
+			assert.Nil(t, err)
+			if format == archiveFormatZip && level == flate.NoCompression {
+				assert.Equal(t, "store", compression)
+			} else if format == archiveFormatZip {
+				assert.Equal(t, "deflate", compression)
+			}
+			assert.Nil(t, writer.Close())
+
+			files, err := readEntries(buffer.Bytes())
+			assert.Nil(t, err)
+			assert.Equal(t, []archiveFile{{name: "trident-controller", content: content}}, files)
+			if format == archiveFormatZip {
+				sizes[level] = buffer.Len()
+			}
+		}
+	}
+
+	assert.True(t, sizes[flate.NoCompression] > len(content))
+	assert.True(t, sizes[flate.BestCompression] < sizes[flate.NoCompression])
+}
+
+func TestArchiveManifestDigests(t *testing.T) {
+
+	savedWriter, savedManifest, savedArchive := archiveWriter, archiveManifest, archive
+	defer func() { archiveWriter, archiveManifest, archive = savedWriter, savedManifest, savedArchive }()
+
+	var buffer bytes.Buffer
+	var err error
+	archiveWriter, err = newArchiveWriter(archiveFormatZip, &buffer)
+	assert.Nil(t, err)
+	archiveManifest, archive = nil, true
+
+	assert.Nil(t, writeArchiveEntry("trident-controller", []byte("line 1\n"), archiveEntryFlags{}))
+	assert.Nil(t, writeStreamedLogEntry("trident-node-a", bytes.NewReader([]byte("line 2\n")),
+		&lineCountingWriter{size: 7, newlines: 1}))
+	assert.Nil(t, writeArchiveManifest())
+	assert.Nil(t, archiveWriter.Close())
+
+	files, err := readZipEntries(buffer.Bytes())
+	assert.Nil(t, err)
+	assert.Len(t, files, 3)
+
+	var manifest []archiveManifestEntry
+	assert.Nil(t, json.Unmarshal(files[2].content, &manifest))
+	assert.Equal(t, "39d031a6c1c196352ec2aea7fb3dc91ff031888b841d140bc400baa403f2d4de", manifest[0].SHA256)
+	for i, entry := range manifest {
+		digest := sha256.Sum256(files[i].content)
+		assert.Equal(t, hex.EncodeToString(digest[:]), entry.SHA256, entry.Name)
+	}
+}
+
+func TestSplitArchiveWriter(t *testing.T) {
+
+	var volumes []*bytes.Buffer
+	writer, err := newSplitArchiveWriter(archiveFormatZip, 100, func(volume int) (io.Writer, func() error, error) {
+		assert.Equal(t, len(volumes)+1, volume)
+		volumes = append(volumes, &bytes.Buffer{})
+		return volumes[volume-1], func() error { return nil }, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, writer.Volume())
+
+	// Each entry is larger than the limit once written, so each starts a new volume
+	entries := []string{"trident-controller", "trident-node-a", "trident-node-b"}
+	for i, name := range entries {
+		_, err = writer.WriteEntry(name, bytes.Repeat([]byte("x"), 200), false)
+		assert.Nil(t, err)
+		assert.Equal(t, i+1, writer.Volume())
+	}
+	assert.Nil(t, writer.Close())
+
+	assert.Len(t, volumes, 3)
+	for i, volume := range volumes {
+		files, err := readZipEntries(volume.Bytes())
+		assert.Nil(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, entries[i], files[0].name)
+	}
+}
+
+func TestArchiveVolumeName(t *testing.T) {
+
+	savedFormat, savedPassword := archiveFormat, archivePassword
+	defer func() { archiveFormat, archivePassword = savedFormat, savedPassword }()
+
+	archiveFormat, archivePassword = archiveFormatZip, ""
+	assert.Equal(t, "support-part001.zip", archiveVolumeName("support.zip", 1))
+
+	archiveFormat, archivePassword = archiveFormatTgz, "secret"
+	assert.Equal(t, "logs/support-part012.tar.gz.enc", archiveVolumeName("logs/support.tar.gz.enc", 12))
+}
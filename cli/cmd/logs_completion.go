@@ -0,0 +1,98 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// The longest a node name completion may wait for the cluster, so that it never blocks the shell
+const nodeCompletionTimeout = 5 * time.Second
+
+// bashCompleteNodes is the bash completion function for the node name flags.  It passes along
+// any namespace given on the command line and completes nothing if the command fails.
+const bashCompleteNodes = `
+__tridentctl_logs_nodes()
+{
+    local namespace_args=() tridentctl_out i
+    for ((i = 0; i < ${#words[@]}; i++)); do
+        case "${words[i]}" in
+            -n|--namespace)
+                namespace_args=(--namespace "${words[i+1]}")
+                ;;
+            --namespace=*)
+                namespace_args=("${words[i]}")
+                ;;
+        esac
+    done
+    if tridentctl_out=$(tridentctl logs __complete-nodes "${namespace_args[@]}" 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${tridentctl_out[*]}" -- "$cur" ) )
+    fi
+}
+`
+
+func init() {
+	logsCmd.AddCommand(logsCompleteNodesCmd)
+	RootCmd.BashCompletionFunction += bashCompleteNodes
+	_ = logsCmd.MarkFlagCustom("node", "__tridentctl_logs_nodes")
+	_ = logsCmd.MarkFlagCustom("exclude-node", "__tridentctl_logs_nodes")
+}
+
+var logsCompleteNodesCmd = &cobra.Command{
+	Use:    "__complete-nodes",
+	Short:  "List the names of the nodes running Trident, for shell completion",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	// Completion must not fail noisily, so the Trident discovery is left to RunE
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		completed := make(chan []string, 1)
+		go func() { completed <- completeNodeNames() }()
+
+		select {
+		case nodeNames := <-completed:
+			for _, nodeName := range nodeNames {
+				fmt.Println(nodeName)
+			}
+		case <-time.After(nodeCompletionTimeout):
+		}
+		return nil
+	},
+}
+
+// completeNodeNames returns the sorted names of the nodes running a Trident node pod, or none if
+// the cluster cannot be reached.
+func completeNodeNames() []string {
+
+	if KubernetesCLI == "" {
+		if err := discoverKubernetesCLI(); err != nil {
+			return nil
+		}
+	}
+	if TridentPodNamespace == "" {
+		var err error
+		if TridentPodNamespace, err = getCurrentNamespace(); err != nil {
+			return nil
+		}
+	}
+
+	tridentNodes, err := listTridentNodes(TridentPodNamespace, "")
+	if err != nil {
+		return nil
+	}
+
+	nodeNames := make([]string, 0, len(tridentNodes))
+	for nodeName := range tridentNodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	return nodeNames
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+const logNameEffectiveConfig = "config"
+
+var effectiveConfig bool
+
+// effectiveConfigSecretKeys are the parts of the configuration keys whose values are redacted,
+// such as password, chapInitiatorSecret, and clientPrivateKey.
+var effectiveConfigSecretKeys = []string{"password", "secret", "token", "privatekey"}
+
+func init() {
+	logsCmd.Flags().BoolVar(&effectiveConfig, "effective-config", false, "Also collect the backend and storage class configuration in effect in the Trident controller, with its secrets redacted.")
+}
+
+// getEffectiveConfig returns the backends and storage classes as the running Trident controller
+// reports them to 'tridentctl get', as YAML with the secrets redacted.
+func getEffectiveConfig() ([]byte, error) {
+
+	// A failure of the tunneled commands should not fail the collection
+	savedExitCode := ExitCode
+	defer func() { ExitCode = savedExitCode }()
+
+	effective := make(map[string]interface{})
+	for _, resource := range []string{"backend", "storageclass"} {
+		output, err := TunnelCommandRaw([]string{"get", resource, "-o", "json"})
+		if err != nil {
+			return nil, fmt.Errorf("could not get the %ss from Trident; %v; %s", resource, err,
+				strings.TrimSpace(string(output)))
+		}
+		var items struct {
+			Items []interface{} `json:"items"`
+		}
+		if err = json.Unmarshal(output, &items); err != nil {
+			return nil, fmt.Errorf("could not parse the %ss from Trident; %v", resource, err)
+		}
+		for _, item := range items.Items {
+			redactConfigSecrets(item)
+		}
+		effective[resource+"s"] = items.Items
+	}
+
+	return yaml.Marshal(effective)
+}
+
+// redactConfigSecrets replaces the values of the secret keys anywhere in a decoded JSON document.
+func redactConfigSecrets(value interface{}) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typedValue {
+			if isConfigSecretKey(key) && child != nil && child != "" {
+				typedValue[key] = redactedValue
+			} else {
+				redactConfigSecrets(child)
+			}
+		}
+	case []interface{}:
+		for _, child := range typedValue {
+			redactConfigSecrets(child)
+		}
+	}
+}
+
+// redactConfigYAML redacts the secrets in a YAML configuration document.
+func redactConfigYAML(document []byte) ([]byte, error) {
+
+	var config interface{}
+	if err := yaml.Unmarshal(document, &config); err != nil {
+		return nil, err
+	}
+	redactConfigSecrets(config)
+
+	return yaml.Marshal(config)
+}
+
+// isConfigSecretKey reports whether a configuration key names a secret.
+func isConfigSecretKey(key string) bool {
+	lowerKey := strings.ToLower(key)
+	for _, secretKey := range effectiveConfigSecretKeys {
+		if strings.Contains(lowerKey, secretKey) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	encryptedArchiveExtension = ".enc"
+
+	// The encrypted archive format is that of 'openssl enc -aes-256-cbc -pbkdf2', so any system
+	// with OpenSSL 1.1.1 or later can decrypt it
+	encryptionSaltHeader = "Salted__"
+	encryptionSaltSize   = 8
+	encryptionIterations = 10000
+	encryptionKeySize    = 32
+)
+
+var (
+	archivePassword      string
+	archivePasswordStdin bool
+)
+
+func init() {
+	logsCmd.Flags().StringVar(&archivePassword, "password", "", "Encrypt the support archive with AES-256 using this password. Decrypt it with 'openssl enc -d -aes-256-cbc -pbkdf2 -in <archive>.enc -out <archive>'.")
+	logsCmd.Flags().BoolVar(&archivePasswordStdin, "password-stdin", false, "Read the support archive password from the first line of stdin, so it does not appear in the process arguments.")
+}
+
+// readArchivePassword checks the password options, reading the password from stdin if requested.
+func readArchivePassword() error {
+
+	if archivePasswordStdin {
+		if archivePassword != "" {
+			return errors.New("--password and --password-stdin cannot be used together")
+		}
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("could not read the password from stdin; %v", err)
+		}
+		archivePassword = strings.TrimRight(line, "\r\n")
+		if archivePassword == "" {
+			return errors.New("the password read from stdin must not be empty")
+		}
+	}
+
+	if archivePassword != "" && (!archive || estimate) {
+		return errors.New("--password and --password-stdin are only supported in archive mode")
+	}
+
+	return nil
+}
+
+// spoolEncryption encrypts a temporary file with AES-256 in CTR mode, using a random key that is
+// never written anywhere, so that the logs of an encrypted support archive are not left on disk
+// in the clear while it is written, nor after a crash.
+type spoolEncryption struct {
+	block cipher.Block
+	iv    []byte
+}
+
+func newSpoolEncryption() (*spoolEncryption, error) {
+
+	keyAndIV := make([]byte, encryptionKeySize+aes.BlockSize)
+	if _, err := rand.Read(keyAndIV); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(keyAndIV[:encryptionKeySize])
+	if err != nil {
+		return nil, err
+	}
+
+	return &spoolEncryption{block: block, iv: keyAndIV[encryptionKeySize:]}, nil
+}
+
+// writer returns a writer encrypting what is written to the file from its start.
+func (e *spoolEncryption) writer(w io.Writer) io.Writer {
+	return cipher.StreamWriter{S: cipher.NewCTR(e.block, e.iv), W: w}
+}
+
+// reader returns a reader decrypting the file from its start.
+func (e *spoolEncryption) reader(r io.Reader) io.Reader {
+	return cipher.StreamReader{S: cipher.NewCTR(e.block, e.iv), R: r}
+}
+
+// encryptingWriter encrypts everything written to it with AES-256 in CBC mode, using a key and
+// IV derived from a password, in the format of 'openssl enc -aes-256-cbc -pbkdf2'.
+type encryptingWriter struct {
+	writer  io.Writer
+	mode    cipher.BlockMode
+	pending []byte
+}
+
+// newEncryptingWriter writes the salt header to the writer and returns an encrypting writer.
+func newEncryptingWriter(w io.Writer, password string) (*encryptingWriter, error) {
+
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyAndIV := pbkdf2.Key([]byte(password), salt, encryptionIterations, encryptionKeySize+aes.BlockSize,
+		sha256.New)
+	block, err := aes.NewCipher(keyAndIV[:encryptionKeySize])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(append([]byte(encryptionSaltHeader), salt...)); err != nil {
+		return nil, err
+	}
+
+	return &encryptingWriter{
+		writer: w,
+		mode:   cipher.NewCBCEncrypter(block, keyAndIV[encryptionKeySize:]),
+	}, nil
+}
+
+func (e *encryptingWriter) Write(p []byte) (int, error) {
+
+	e.pending = append(e.pending, p...)
+
+	// Encrypt every complete block, keeping the rest until more is written or the writer is closed
+	complete := len(e.pending) - len(e.pending)%aes.BlockSize
+	if complete == 0 {
+		return len(p), nil
+	}
+	encrypted := make([]byte, complete)
+	e.mode.CryptBlocks(encrypted, e.pending[:complete])
+	e.pending = append(e.pending[:0], e.pending[complete:]...)
+
+	if _, err := e.writer.Write(encrypted); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close pads and encrypts the final block without closing the underlying writer.
+func (e *encryptingWriter) Close() error {
+
+	padding := aes.BlockSize - len(e.pending)%aes.BlockSize
+	for i := 0; i < padding; i++ {
+		e.pending = append(e.pending, byte(padding))
+	}
+	e.mode.CryptBlocks(e.pending, e.pending)
+
+	_, err := e.writer.Write(e.pending)
+	e.pending = nil
+	return err
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// decryptArchive decrypts the output of an encrypting writer as 'openssl enc -d' would.
+func decryptArchive(t *testing.T, encrypted []byte, password string) []byte {
+
+	assert.True(t, bytes.HasPrefix(encrypted, []byte(encryptionSaltHeader)))
+	salt := encrypted[len(encryptionSaltHeader) : len(encryptionSaltHeader)+encryptionSaltSize]
+	ciphertext := encrypted[len(encryptionSaltHeader)+encryptionSaltSize:]
+	assert.Zero(t, len(ciphertext)%aes.BlockSize)
+
+	keyAndIV := pbkdf2.Key([]byte(password), salt, encryptionIterations, encryptionKeySize+aes.BlockSize,
+		sha256.New)
+	block, err := aes.NewCipher(keyAndIV[:encryptionKeySize])
+	assert.Nil(t, err)
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, keyAndIV[encryptionKeySize:]).CryptBlocks(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	assert.True(t, padding >= 1 && padding <= aes.BlockSize)
+	return plaintext[:len(plaintext)-padding]
+}
+
+func TestEncryptingWriter(t *testing.T) {
+
+	for _, size := range []int{0, 1, aes.BlockSize, 100} {
+		content := bytes.Repeat([]byte("a"), size)
+
+		var encrypted bytes.Buffer
+		writer, err := newEncryptingWriter(&encrypted, "secret")
+		assert.Nil(t, err)
+
+		// Write in uneven pieces to exercise the buffering of partial blocks
+		for start := 0; start < len(content); start += 7 {
+			end := start + 7
+			if end > len(content) {
+				end = len(content)
+			}
+			n, err := writer.Write(content[start:end])
+			assert.Nil(t, err)
+			assert.Equal(t, end-start, n)
+		}
+		assert.Nil(t, writer.Close())
+
+		assert.Equal(t, content, decryptArchive(t, encrypted.Bytes(), "secret"), "size %d", size)
+		assert.NotContains(t, encrypted.String(), "aaaaaaaa")
+	}
+}
+
+func TestSpoolContainerLogsEncrypted(t *testing.T) {
+
+	restore := useFakeCommandRunner(&fakeCommandRunner{outputs: map[string]string{
+		"logs trident-csi-a -n trident -c trident-main --previous=false": "level=info msg=\"Node started.\"\n",
+	}})
+	defer restore()
+	savedPassword := archivePassword
+	defer func() { archivePassword = savedPassword }()
+	archive, archivePassword = true, "secret"
+
+	spoolFile, spoolContent, spoolWriter, err := spoolContainerLogs("trident-csi-a", "trident-main", false)
+	assert.Nil(t, err)
+	defer os.Remove(spoolFile.Name())
+	defer spoolFile.Close()
+
+	onDisk, err := ioutil.ReadFile(spoolFile.Name())
+	assert.Nil(t, err)
+	assert.Len(t, onDisk, int(spoolWriter.size))
+	assert.NotContains(t, string(onDisk), "Node started")
+
+	content, err := ioutil.ReadAll(spoolContent)
+	assert.Nil(t, err)
+	assert.Equal(t, "level=info msg=\"Node started.\"\n", string(content))
+}
+
+func TestReadArchivePassword(t *testing.T) {
+
+	savedPassword, savedStdin, savedArchive, savedEstimate := archivePassword, archivePasswordStdin, archive, estimate
+	defer func() {
+		archivePassword, archivePasswordStdin, archive, estimate = savedPassword, savedStdin, savedArchive, savedEstimate
+	}()
+
+	archivePassword, archivePasswordStdin, archive, estimate = "secret", false, true, false
+	assert.Nil(t, readArchivePassword())
+
+	archive = false
+	assert.EqualError(t, readArchivePassword(), "--password and --password-stdin are only supported in archive mode")
+
+	archivePasswordStdin = true
+	assert.EqualError(t, readArchivePassword(), "--password and --password-stdin cannot be used together")
+}
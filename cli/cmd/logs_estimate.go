@@ -0,0 +1,218 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	k8s "k8s.io/api/core/v1"
+)
+
+const (
+	// The most recent lines of a log read to measure the length of its lines
+	estimateSampleLines = 200
+	// The most bytes of a log read for its sample
+	estimateSampleBytes = 256 * 1024
+)
+
+// The bases of a log size estimate, as listed in the estimate table
+const (
+	estimateBasisExact   = "exact"
+	estimateBasisKubelet = "kubelet log size"
+	estimateBasisTail    = "sampled lines x --tail"
+	estimateBasisSample  = "sample only, at least"
+)
+
+var (
+	// The container log sizes reported by the kubelet of each node, read once per node
+	kubeletLogSizes     = make(map[string]*nodeLogSizes)
+	kubeletLogSizesLock sync.Mutex
+)
+
+// nodeLogSizes are the container log sizes reported by the kubelet of a node, keyed by
+// namespace/pod/container, or the error reading them.
+type nodeLogSizes struct {
+	sizes map[string]uint64
+	err   error
+}
+
+// kubeletStatsSummary is the part of the kubelet stats summary that reports container log sizes.
+type kubeletStatsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name string `json:"name"`
+			Logs *struct {
+				UsedBytes *uint64 `json:"usedBytes"`
+			} `json:"logs"`
+		} `json:"containers"`
+	} `json:"pods"`
+}
+
+// estimateContainerLog estimates the size of a container log without reading all of it, and
+// records the estimate and its outcome in the collection results.  A failure to estimate an
+// optional log, such as the previous log of a container that has not restarted, is not recorded.
+func estimateContainerLog(result collectionResult, optional bool) (int, error) {
+
+	estimate, err := estimateLogSize(result)
+
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	if err != nil {
+		if optional {
+			return 0, err
+		}
+		logErrors.add(err.Error())
+		result.Error = err.Error()
+	} else {
+		result.Bytes = estimate.Bytes
+		if archiveEntrySelected(result.Name) {
+			logEstimates = append(logEstimates, estimate)
+		}
+	}
+
+	collectionResults = append(collectionResults, result)
+
+	return result.Bytes, err
+}
+
+// estimateLogSize reads a sample of the most recent lines of a container log to measure the
+// length of its lines.  A log no longer than the sample is measured exactly.  Otherwise its size
+// is the size of its file reported by the kubelet of the pod's node, or the --tail line count
+// times the line length if smaller.  The kubelet reports only the size of the current log, and
+// counts lines outside any --since or time range, so the estimate is only an approximation.
+func estimateLogSize(result collectionResult) (logEstimate, error) {
+
+	estimate := logEstimate{Name: logNamespacePrefix + result.Name, Basis: estimateBasisExact}
+
+	sampleCommand := sampleLogsCommand(result.Pod, result.Container, result.Previous)
+	printInvokedCommand(KubernetesCLI, sampleCommand)
+	sample, err := retryTransientFailures(func() ([]byte, error) {
+		return cliRunner.Run(KubernetesCLI, sampleCommand...)
+	})
+	if err != nil {
+		return estimate, err
+	}
+
+	estimate.Lines, estimate.Bytes = countLines(sample), len(sample)
+	tailSampled := tailLines >= 0 && tailLines <= estimateSampleLines
+	if len(sample) < estimateSampleBytes && (estimate.Lines < estimateSampleLines || tailSampled) {
+		return estimate, nil
+	}
+
+	lineBytes := float64(len(sample))
+	if estimate.Lines > 0 {
+		lineBytes /= float64(estimate.Lines)
+	}
+
+	estimate.Basis = estimateBasisSample
+	if !result.Previous {
+		if size, sizeErr := kubeletLogSize(result); sizeErr == nil {
+			estimate.Bytes, estimate.Basis = int(size), estimateBasisKubelet
+		} else if Debug {
+			fmt.Printf("Estimating the size of log %s without the kubelet; %v\n", result.Name, sizeErr)
+		}
+	}
+	if tailBytes := int(float64(tailLines) * lineBytes); tailLines >= 0 &&
+		(estimate.Basis == estimateBasisSample || tailBytes < estimate.Bytes) {
+		estimate.Bytes, estimate.Basis = tailBytes, estimateBasisTail
+	}
+	if estimate.Basis != estimateBasisSample {
+		estimate.Lines = int(float64(estimate.Bytes) / lineBytes)
+	}
+
+	return estimate, nil
+}
+
+// sampleLogsCommand returns the Kubernetes CLI command that gets the most recent lines of a
+// container log selected by the command options, limited to the size of a sample.
+func sampleLogsCommand(pod, container string, prev bool) []string {
+
+	sampleLines := int64(estimateSampleLines)
+	if tailLines >= 0 && tailLines < sampleLines {
+		sampleLines = tailLines
+	}
+
+	logsCommand := make([]string, 0)
+	for _, arg := range buildLogsCommand(pod, container, prev) {
+		if !strings.HasPrefix(arg, "--tail=") {
+			logsCommand = append(logsCommand, arg)
+		}
+	}
+
+	return append(logsCommand, fmt.Sprintf("--tail=%d", sampleLines),
+		fmt.Sprintf("--limit-bytes=%d", estimateSampleBytes))
+}
+
+// kubeletLogSize returns the size of the current log file of a container, as reported by the
+// kubelet of the node running its pod.
+func kubeletLogSize(result collectionResult) (uint64, error) {
+
+	nodeName := result.Node
+	if nodeName == "" {
+		var pod k8s.Pod
+		if err := getKubernetesObjects(&pod, "get", "pod", result.Pod, "-n", TridentPodNamespace,
+			"-o=json"); err != nil {
+			return 0, fmt.Errorf("could not get pod %s; %v", result.Pod, err)
+		}
+		nodeName = pod.Spec.NodeName
+	}
+
+	logSizes := getKubeletLogSizes(nodeName)
+	if logSizes.err != nil {
+		return 0, logSizes.err
+	}
+
+	size, ok := logSizes.sizes[TridentPodNamespace+"/"+result.Pod+"/"+result.Container]
+	if !ok {
+		return 0, fmt.Errorf("the kubelet of node %s does not report the log size of container %s in pod %s",
+			nodeName, result.Container, result.Pod)
+	}
+	return size, nil
+}
+
+// getKubeletLogSizes returns the container log sizes in the stats summary of the kubelet of a
+// node, which is read only once.
+func getKubeletLogSizes(nodeName string) *nodeLogSizes {
+
+	kubeletLogSizesLock.Lock()
+	defer kubeletLogSizesLock.Unlock()
+
+	if logSizes, ok := kubeletLogSizes[nodeName]; ok {
+		return logSizes
+	}
+
+	logSizes := &nodeLogSizes{sizes: make(map[string]uint64)}
+	kubeletLogSizes[nodeName] = logSizes
+
+	statsCommand := []string{"get", "--raw", "/api/v1/nodes/" + nodeName + "/proxy/stats/summary"}
+	printInvokedCommand(KubernetesCLI, statsCommand)
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(statsCommand...)...)
+	if err != nil {
+		logSizes.err = fmt.Errorf("could not read the kubelet stats of node %s; %v", nodeName, err)
+		return logSizes
+	}
+
+	var summary kubeletStatsSummary
+	if err = json.Unmarshal(output, &summary); err != nil {
+		logSizes.err = fmt.Errorf("could not read the kubelet stats of node %s; %v", nodeName, err)
+		return logSizes
+	}
+	for _, pod := range summary.Pods {
+		for _, container := range pod.Containers {
+			if container.Logs != nil && container.Logs.UsedBytes != nil {
+				logSizes.sizes[pod.PodRef.Namespace+"/"+pod.PodRef.Name+"/"+container.Name] =
+					*container.Logs.UsedBytes
+			}
+		}
+	}
+
+	return logSizes
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleLogsCommandNode1 = "logs trident-csi-a -n trident -c trident-main --previous=false --tail=200 " +
+	"--limit-bytes=262144"
+
+func TestEstimateLogSize(t *testing.T) {
+
+	// A full sample of 200 lines of 100 bytes each
+	fullSample := strings.Repeat(strings.Repeat("x", 99)+"\n", estimateSampleLines)
+	runner := &fakeCommandRunner{outputs: map[string]string{
+		sampleLogsCommandNode1: "level=info msg=\"Node started.\"\n",
+		"get --raw /api/v1/nodes/node1/proxy/stats/summary": `{"pods": [{"podRef": {"name": "trident-csi-a",
+			"namespace": "trident"}, "containers": [{"name": "trident-main", "logs": {"usedBytes": 1000000}}]}]}`,
+	}}
+	defer useFakeCommandRunner(runner)()
+
+	savedTailLines, savedLogSizes := tailLines, kubeletLogSizes
+	defer func() { tailLines, kubeletLogSizes = savedTailLines, savedLogSizes }()
+	tailLines, kubeletLogSizes = -1, make(map[string]*nodeLogSizes)
+
+	result := collectionResult{Name: "trident-node-node1", Pod: "trident-csi-a", Container: "trident-main",
+		Node: "node1"}
+
+	// A log shorter than the sample is measured exactly
+	estimate, err := estimateLogSize(result)
+	assert.Nil(t, err)
+	assert.Equal(t, logEstimate{Name: "trident-node-node1", Lines: 1, Bytes: 31, Basis: estimateBasisExact}, estimate)
+
+	// A longer log is the size reported by the kubelet, in lines of the sampled length
+	runner.outputs[sampleLogsCommandNode1] = fullSample
+	estimate, err = estimateLogSize(result)
+	assert.Nil(t, err)
+	assert.Equal(t, logEstimate{Name: "trident-node-node1", Lines: 10000, Bytes: 1000000,
+		Basis: estimateBasisKubelet}, estimate)
+
+	// Unless --tail limits it to fewer lines
+	tailLines = 500
+	estimate, err = estimateLogSize(result)
+	assert.Nil(t, err)
+	assert.Equal(t, logEstimate{Name: "trident-node-node1", Lines: 500, Bytes: 50000, Basis: estimateBasisTail},
+		estimate)
+
+	// Without the kubelet stats, the sample is all that is known
+	tailLines, kubeletLogSizes = -1, make(map[string]*nodeLogSizes)
+	delete(runner.outputs, "get --raw /api/v1/nodes/node1/proxy/stats/summary")
+	estimate, err = estimateLogSize(result)
+	assert.Nil(t, err)
+	assert.Equal(t, logEstimate{Name: "trident-node-node1", Lines: 200, Bytes: 20000, Basis: estimateBasisSample},
+		estimate)
+
+	// The stats of a node are read only once
+	runner.commands = nil
+	_, err = estimateLogSize(result)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{sampleLogsCommandNode1}, runner.commands)
+}
+
+func TestSampleLogsCommand(t *testing.T) {
+
+	defer useFakeCommandRunner(&fakeCommandRunner{})()
+
+	savedTailLines, savedSince := tailLines, since
+	defer func() { tailLines, since = savedTailLines, savedSince }()
+
+	tailLines, since = 50, "1h"
+	assert.Equal(t, []string{"logs", "trident-csi-a", "-n", "trident", "-c", "trident-main", "--previous=true",
+		"--since=1h", "--tail=50", "--limit-bytes=262144"}, sampleLogsCommand("trident-csi-a", "trident-main", true))
+}
+
+func TestCollectContainerLogsEstimate(t *testing.T) {
+
+	runner := &fakeCommandRunner{outputs: map[string]string{
+		sampleLogsCommandNode1: "level=info msg=\"Node started.\"\n",
+	}}
+	defer useFakeCommandRunner(runner)()
+
+	savedEstimate, savedTailLines, savedEstimates := estimate, tailLines, logEstimates
+	defer func() { estimate, tailLines, logEstimates = savedEstimate, savedTailLines, savedEstimates }()
+	estimate, tailLines, logEstimates = true, -1, nil
+
+	logBytes, err := collectContainerLogs("trident-node-node1", "trident-csi-a", "trident-main", "node1", false)
+	assert.Nil(t, err)
+	assert.Nil(t, logBytes)
+	assert.Equal(t, []string{sampleLogsCommandNode1}, runner.commands)
+	assert.Equal(t, []logEstimate{{Name: "trident-node-node1", Lines: 1, Bytes: 31, Basis: estimateBasisExact}},
+		logEstimates)
+	assert.Equal(t, []collectionResult{{Name: "trident-node-node1", Namespace: "trident", Pod: "trident-csi-a",
+		Container: "trident-main", Node: "node1", Bytes: 31}}, collectionResults)
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/netapp/trident/config"
+)
+
+const defaultMaxMatches = 100
+
+var maxMatches int
+
+// findMatch is one line of Trident state or logs that contains the query.
+type findMatch struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+}
+
+type findMatchResponse struct {
+	Items     []findMatch `json:"items"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+func init() {
+	logsCmd.AddCommand(logsFindCmd)
+	logsFindCmd.Flags().IntVar(&maxMatches, "max-matches", defaultMaxMatches, "The maximum number of matches to report.")
+}
+
+var logsFindCmd = &cobra.Command{
+	Use:   "find <query>",
+	Short: "Search the Trident custom resources and logs for a string",
+	Long: "Search the Trident custom resources and the controller and node logs for a string, " +
+		"reporting each matching line with its source",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if args[0] == "" {
+			return errors.New("the query must not be empty")
+		}
+		if maxMatches < 1 {
+			return fmt.Errorf("%d is not a valid --max-matches limit", maxMatches)
+		}
+
+		matches, truncated, err := findInTrident(args[0], maxMatches)
+		if err != nil {
+			return err
+		}
+
+		writeFindMatches(matches, truncated)
+		return nil
+	},
+}
+
+// findInTrident searches the Trident custom resources, then the controller log, then each node
+// log for the query, stopping once the match limit is reached.  Sources that cannot be read are
+// skipped with a warning, since a partial search is still useful.
+func findInTrident(query string, limit int) ([]findMatch, bool, error) {
+
+	if OperatingMode != ModeTunnel {
+		return nil, false, errors.New("'tridentctl logs find' requires access to the Kubernetes CLI")
+	}
+
+	matches := make([]findMatch, 0)
+	searchSource := func(source string, content []byte) bool {
+		matches = append(matches, findInContent(source, content, query, limit-len(matches))...)
+		return len(matches) >= limit
+	}
+
+	for _, crdName := range CRDnames {
+		resource := strings.Split(crdName, ".")[0]
+		stateCommand := []string{"get", crdName, "-n", TridentPodNamespace, "-o=yaml"}
+		output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(stateCommand...)...)
+		if err != nil {
+			logFindWarning(resource, err)
+			continue
+		}
+		if searchSource(resource, output) {
+			return matches, true, nil
+		}
+	}
+
+	output, err := getContainerLogs(TridentPodName, config.ContainerTrident, false)
+	if err != nil {
+		logFindWarning(logNameTrident, err)
+	} else if searchSource(logNameTrident, output) {
+		return matches, true, nil
+	}
+
+	tridentNodes, err := listTridentNodes(TridentPodNamespace, "")
+	if err != nil {
+		logFindWarning(logNameNode, err)
+		return matches, false, nil
+	}
+
+	nodeNames := make([]string, 0, len(tridentNodes))
+	for nodeName := range tridentNodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	for _, nodeName := range nodeNames {
+		source := "trident-node-" + nodeName
+		output, err := getContainerLogs(tridentNodes[nodeName], config.ContainerTrident, false)
+		if err != nil {
+			logFindWarning(source, err)
+			continue
+		}
+		if searchSource(source, output) {
+			return matches, true, nil
+		}
+	}
+
+	return matches, false, nil
+}
+
+// findInContent returns up to limit lines of the content that contain the query.
+func findInContent(source string, content []byte, query string, limit int) []findMatch {
+
+	matches := make([]findMatch, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan() && len(matches) < limit; lineNumber++ {
+		if line := scanner.Text(); strings.Contains(line, query) {
+			matches = append(matches, findMatch{Source: source, Line: lineNumber, Text: line})
+		}
+	}
+
+	return matches
+}
+
+func logFindWarning(source string, err error) {
+	fmt.Fprintf(os.Stderr, "Could not search %s; %v\n", source, err)
+}
+
+func writeFindMatches(matches []findMatch, truncated bool) {
+	switch OutputFormat {
+	case FormatJSON:
+		WriteJSON(findMatchResponse{Items: matches, Truncated: truncated})
+	case FormatYAML:
+		WriteYAML(findMatchResponse{Items: matches, Truncated: truncated})
+	default:
+		for _, match := range matches {
+			fmt.Printf("%s:%d: %s\n", match.Source, match.Line, match.Text)
+		}
+		if truncated {
+			fmt.Printf("Stopped after %d matches.\n", len(matches))
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/pflag"
+)
+
+const archiveIndexName = "INDEX.txt"
+
+var (
+	// When the archive collection started, and the versions it found, for the archive index
+	archiveStartTime time.Time
+	archiveVersion   *versionInfo
+	// The flags specified, with any credentials redacted
+	archiveIndexArgs []string
+)
+
+// writeArchiveIndex adds an index summarizing the collection and every other entry, so that the
+// support archive can be understood without the command that created it.
+func writeArchiveIndex() error {
+	_, err := archiveWriter.WriteEntry(archiveIndexName, buildArchiveIndex(time.Now()), true)
+	return err
+}
+
+// buildArchiveIndex returns the text of the archive index, from the collection results and the
+// archive manifest.
+func buildArchiveIndex(endTime time.Time) []byte {
+
+	var index bytes.Buffer
+	fmt.Fprintln(&index, "Trident support archive")
+	fmt.Fprintln(&index)
+
+	header := tabwriter.NewWriter(&index, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(header, "Collected:\t%s to %s\n", archiveStartTime.Format(time.RFC3339), endTime.Format(time.RFC3339))
+	fmt.Fprintf(header, "Context:\t%s\n", archiveIndexContext())
+	if allNamespaces {
+		fmt.Fprintf(header, "Namespace:\tall namespaces with Trident\n")
+	} else {
+		fmt.Fprintf(header, "Namespace:\t%s\n", TridentPodNamespace)
+	}
+	fmt.Fprintf(header, "Trident version:\t%s\n", archiveIndexVersion())
+	fmt.Fprintf(header, "Command:\t%s\n", strings.Join(append([]string{"tridentctl", "logs"},
+		archiveIndexArgs...), " "))
+	_ = header.Flush()
+
+	collected, failed := 0, 0
+	for _, result := range collectionResults {
+		if result.Error == "" {
+			collected++
+		} else {
+			failed++
+		}
+	}
+	fmt.Fprintf(&index, "\nLogs collected: %d, failed: %d\n", collected, failed)
+	for _, result := range collectionResults {
+		if result.Error != "" {
+			fmt.Fprintf(&index, "  %s: %s\n", result.Name, maskString(result.Error))
+		}
+	}
+
+	fmt.Fprintf(&index, "\nEntries (the SHA-256 digest of each is in %s):\n", archiveManifestName)
+	entries := tabwriter.NewWriter(&index, 0, 8, 2, ' ', 0)
+	for _, entry := range archiveManifest {
+		fmt.Fprintf(entries, "  %s\t%s", entry.Name, humanize.Bytes(uint64(entry.Size)))
+		var notes []string
+		if entry.Part > 0 {
+			notes = append(notes, fmt.Sprintf("volume %d", entry.Part))
+		}
+		if entry.Truncated {
+			notes = append(notes, "truncated")
+		}
+		if entry.Redacted {
+			notes = append(notes, "redacted")
+		}
+		if entry.Filtered {
+			notes = append(notes, "filtered")
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(entries, " (%s)", strings.Join(notes, ", "))
+		}
+		fmt.Fprintln(entries)
+	}
+	_ = entries.Flush()
+
+	return index.Bytes()
+}
+
+// archiveIndexContext returns the kubeconfig context the logs were collected from.
+func archiveIndexContext() string {
+
+	if KubernetesContext != "" {
+		return KubernetesContext
+	}
+	if InCluster {
+		return "in-cluster"
+	}
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs("config", "current-context")...)
+	if err != nil || strings.TrimSpace(string(output)) == "" {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// archiveIndexVersion returns the version of the running Trident controller, if it was found.
+func archiveIndexVersion() string {
+	if archiveVersion == nil || archiveVersion.Server == nil {
+		return "unknown"
+	}
+	return archiveVersion.Server.Version
+}
+
+// archiveIndexFlags returns the flags that were specified, with any credentials they contain
+// redacted.
+func archiveIndexFlags(flags *pflag.FlagSet) []string {
+
+	var args []string
+	flags.Visit(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "password":
+			args = append(args, "--"+flag.Name+"="+redactedValue)
+		case "upload-url", "notify":
+			args = append(args, "--"+flag.Name+"="+redactedURL(flag.Value.String()))
+		default:
+			if flag.Value.Type() == "stringArray" {
+				values, _ := flags.GetStringArray(flag.Name)
+				for _, value := range values {
+					args = append(args, "--"+flag.Name+"="+value)
+				}
+			} else if flag.Value.Type() == "bool" && flag.Value.String() == "true" {
+				args = append(args, "--"+flag.Name)
+			} else {
+				args = append(args, "--"+flag.Name+"="+flag.Value.String())
+			}
+		}
+	})
+
+	return args
+}
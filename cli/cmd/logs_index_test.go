@@ -0,0 +1,69 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/netapp/trident/cli/api"
+)
+
+func TestBuildArchiveIndex(t *testing.T) {
+
+	restore := useFakeCommandRunner(&fakeCommandRunner{outputs: map[string]string{
+		"config current-context": "prod-cluster\n",
+	}})
+	defer restore()
+
+	savedManifest, savedArgs := archiveManifest, archiveIndexArgs
+	defer func() {
+		archiveManifest, archiveIndexArgs, archiveStartTime, archiveVersion = savedManifest, savedArgs, time.Time{}, nil
+	}()
+
+	archiveStartTime = time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+	archiveVersion = &versionInfo{Server: &api.Version{Version: "20.07.0"}}
+	archiveIndexArgs = []string{"--archive", "--since=1h"}
+	collectionResults = []collectionResult{
+		{Name: "trident", Bytes: 2048},
+		{Name: "trident-node-a", Error: "container not found"},
+	}
+	archiveManifest = []archiveManifestEntry{
+		{Name: "trident", Size: 2048},
+		{Name: "trident-node-a", Size: 10, archiveEntryFlags: archiveEntryFlags{Truncated: true, Redacted: true}},
+	}
+
+	assert.Equal(t, `Trident support archive
+
+Collected:        2020-06-01T10:00:00Z to 2020-06-01T10:05:00Z
+Context:          prod-cluster
+Namespace:        trident
+Trident version:  20.07.0
+Command:          tridentctl logs --archive --since=1h
+
+Logs collected: 1, failed: 1
+  trident-node-a: container not found
+
+Entries (the SHA-256 digest of each is in manifest.json):
+  trident         2.0 kB
+  trident-node-a  10 B (truncated, redacted)
+`, string(buildArchiveIndex(archiveStartTime.Add(5*time.Minute))))
+}
+
+func TestArchiveIndexFlags(t *testing.T) {
+
+	flags := pflag.NewFlagSet("logs", pflag.ContinueOnError)
+	flags.Bool("archive", false, "")
+	flags.String("password", "", "")
+	flags.String("upload-url", "", "")
+	flags.StringArray("include", nil, "")
+	flags.String("since", "", "")
+	assert.Nil(t, flags.Parse([]string{"--archive", "--password=secret", "--include=trident*",
+		"--include=events", "--upload-url=https://example.com/upload?signature=abc"}))
+
+	assert.Equal(t, []string{"--archive", "--include=trident*", "--include=events", "--password=<REDACTED>",
+		"--upload-url=https://example.com/upload"}, archiveIndexFlags(flags))
+}
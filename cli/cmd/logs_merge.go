@@ -0,0 +1,128 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/netapp/trident/config"
+)
+
+const (
+	logNameNodesMerged         = "trident-nodes-merged"
+	logNameNodesMergedPrevious = "trident-nodes-merged-previous"
+)
+
+var (
+	mergeNodeLogs bool
+	// The lines of the node logs collected so far, when merging them
+	mergedNodeLines []timedLogLine
+	// Whether any node log was collected for the merged log, even if it had no lines
+	mergedNodeLogCollected bool
+	// How the lines of the merged node logs were selected
+	mergedNodeFlags archiveEntryFlags
+)
+
+func init() {
+	logsCmd.Flags().BoolVar(&mergeNodeLogs, "merge", false, "Merge the Trident node logs into one log ordered by time, each line prefixed with its node. Requires --timestamps.")
+}
+
+// checkValidMerge checks that the node logs are only merged when they have timestamps to order
+// them by.
+func checkValidMerge() error {
+	if !mergeNodeLogs {
+		return nil
+	}
+	if !timestamps {
+		return errors.New("--merge requires --timestamps")
+	}
+	if follow || estimate {
+		return errors.New("--merge cannot be used with --follow or --estimate")
+	}
+	return nil
+}
+
+// collectNodeLogs collects the log of a Trident node container, adding its lines to the merged
+// node log if merging rather than writing it on its own.
+func collectNodeLogs(logName, pod, container, nodeName string, prev bool) {
+	if mergeNodeLogs && container == config.ContainerTrident {
+		collectMergedNodeLog(logName, pod, container, nodeName, prev)
+	} else {
+		collectContainerLogs(logName, pod, container, nodeName, prev)
+	}
+}
+
+// collectMergedNodeLog gets the log of a Trident node container and adds its selected lines to
+// the merged node log.  As when writing it on its own, the log of the previous container instance
+// is written too if the log is empty.
+func collectMergedNodeLog(logName, pod, container, nodeName string, prev bool) {
+
+	result := collectionResult{Name: logName, Namespace: TridentPodNamespace, Pod: pod, Container: container,
+		Node: nodeName, Previous: prev}
+	logBytes, err := getContainerLogs(pod, container, prev)
+
+	logsLock.Lock()
+
+	if err != nil {
+		logErrors.add(err.Error())
+		result.Error = err.Error()
+	} else {
+		result.Bytes = len(logBytes)
+		mergedNodeLogCollected = true
+		// The source prefix hides the timestamps from the until filter and the start of each line
+		// from --grep, so select the lines of each log first
+		if !logUntilTime.IsZero() {
+			logBytes = filterLogsUntil(logBytes, logUntilTime, true)
+		}
+		if selectedBytes, selected := selectLogLines(logBytes, &mergedNodeFlags); selected {
+			mergedNodeLines = append(mergedNodeLines, timeLogLines(logName, selectedBytes)...)
+		}
+	}
+
+	collectionResults = append(collectionResults, result)
+
+	logsLock.Unlock()
+
+	if err == nil && result.Bytes == 0 && !prev && fallbackToPrevious() {
+		previousResult := result
+		previousResult.Name, previousResult.Previous, previousResult.Bytes = previousLogName(logName), true, 0
+		_, _, _ = collectContainerLog(previousResult, true)
+	}
+}
+
+// writeMergedNodeLog writes the merged current or previous node log, if any node log was
+// collected, even if none of them had any lines.
+func writeMergedNodeLog(logName string) error {
+
+	defer func() {
+		mergedNodeLines, mergedNodeLogCollected, mergedNodeFlags = nil, false, archiveEntryFlags{}
+	}()
+
+	if !mergeNodeLogs || !mergedNodeLogCollected {
+		return nil
+	}
+
+	mergedLogName := logNameNodesMerged
+	if logName == logNameNodePrevious {
+		mergedLogName = logNameNodesMergedPrevious
+	}
+	if err := writeSelectedLogs(mergedLogName, mergeTimedLogLines(mergedNodeLines), mergedNodeFlags); err != nil {
+		return fmt.Errorf("could not write log %s; %v", mergedLogName, err)
+	}
+	return nil
+}
+
+// mergeTimedLogLines orders log lines by time, keeping the order of lines with the same time.
+func mergeTimedLogLines(timedLines []timedLogLine) []byte {
+
+	sort.SliceStable(timedLines, func(i, j int) bool { return timedLines[i].time.Before(timedLines[j].time) })
+
+	var merged bytes.Buffer
+	for _, line := range timedLines {
+		merged.WriteString(line.line)
+	}
+	return merged.Bytes()
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSelectedNodeLogsMerged(t *testing.T) {
+
+	runner := &fakeCommandRunner{
+		outputs: map[string]string{
+			listNodePodsCommand: nodePodListJSON(t, map[string]string{"node1": "trident-csi-a", "node2": "trident-csi-b"}),
+			"logs trident-csi-a -n trident -c trident-main --previous=false --timestamps": "" +
+				"2020-01-20T15:04:01Z level=info msg=\"Node started.\"\n" +
+				"2020-01-20T15:04:03Z level=error msg=\"Mount failed.\"\n" +
+				"goroutine 1 [running]:\n",
+			"logs trident-csi-b -n trident -c trident-main --previous=false --timestamps": "" +
+				"2020-01-20T15:04:02Z level=info msg=\"Node started.\"\n",
+		},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	savedMerge, savedTimestamps := mergeNodeLogs, timestamps
+	defer func() { mergeNodeLogs, timestamps = savedMerge, savedTimestamps }()
+	mergeNodeLogs, timestamps = true, true
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	assert.Nil(t, getSelectedNodeLogs(logNameNode))
+	assert.Equal(t, "trident-nodes-merged log:\n"+
+		"[trident-node-node1] 2020-01-20T15:04:01Z level=info msg=\"Node started.\"\n"+
+		"[trident-node-node2] 2020-01-20T15:04:02Z level=info msg=\"Node started.\"\n"+
+		"[trident-node-node1] 2020-01-20T15:04:03Z level=error msg=\"Mount failed.\"\n"+
+		"[trident-node-node1] goroutine 1 [running]:\n\n", console.String())
+	assert.Len(t, collectionResults, 2)
+	assert.Nil(t, mergedNodeLines)
+}
+
+func TestCheckValidMerge(t *testing.T) {
+
+	savedMerge, savedTimestamps, savedFollow := mergeNodeLogs, timestamps, follow
+	defer func() { mergeNodeLogs, timestamps, follow = savedMerge, savedTimestamps, savedFollow }()
+
+	mergeNodeLogs, timestamps, follow = true, false, false
+	assert.EqualError(t, checkValidMerge(), "--merge requires --timestamps")
+
+	timestamps = true
+	assert.Nil(t, checkValidMerge())
+
+	follow = true
+	assert.NotNil(t, checkValidMerge())
+}
+
+func TestGetSelectedNodeLogsMergedGrep(t *testing.T) {
+
+	runner := &fakeCommandRunner{
+		outputs: map[string]string{
+			listNodePodsCommand: nodePodListJSON(t, map[string]string{"node1": "trident-csi-a"}),
+			"logs trident-csi-a -n trident -c trident-main --previous=false --timestamps": "" +
+				"2020-01-20T15:04:01Z level=info msg=\"Node started.\"\n" +
+				"2020-01-20T15:04:03Z level=error msg=\"Mount failed.\"\n",
+		},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	savedMerge, savedTimestamps, savedGrepRegex := mergeNodeLogs, timestamps, grepRegex
+	defer func() { mergeNodeLogs, timestamps, grepRegex = savedMerge, savedTimestamps, savedGrepRegex }()
+	mergeNodeLogs, timestamps, grepRegex = true, true, regexp.MustCompile(`^\S+ level=error`)
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	// The pattern is matched against the lines before they are prefixed with their node
+	assert.Nil(t, getSelectedNodeLogs(logNameNode))
+	assert.Equal(t, "trident-nodes-merged log:\n"+
+		"[trident-node-node1] 2020-01-20T15:04:03Z level=error msg=\"Mount failed.\"\n\n", console.String())
+}
+
+func TestGetSelectedNodeLogsMergedEmpty(t *testing.T) {
+
+	runner := &fakeCommandRunner{
+		outputs: map[string]string{
+			listNodePodsCommand: nodePodListJSON(t, map[string]string{"node1": "trident-csi-a"}),
+			"logs trident-csi-a -n trident -c trident-main --previous=false --timestamps": "",
+			"logs trident-csi-a -n trident -c trident-main --previous=true --timestamps": "" +
+				"2020-01-20T15:04:01Z level=fatal msg=\"Crashed.\"\n",
+		},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	savedMerge, savedTimestamps, savedLogType := mergeNodeLogs, timestamps, logType
+	defer func() { mergeNodeLogs, timestamps, logType = savedMerge, savedTimestamps, savedLogType }()
+	mergeNodeLogs, timestamps, logType = true, true, logTypeAuto
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	// An empty node log is still written, along with the log of the previous instance
+	assert.Nil(t, getSelectedNodeLogs(logNameNode))
+	assert.Equal(t, "trident-node-node1-previous log:\n2020-01-20T15:04:01Z level=fatal msg=\"Crashed.\"\n\n"+
+		"trident-nodes-merged log:\n\n", console.String())
+	assert.Len(t, collectionResults, 2)
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	k8s "k8s.io/api/core/v1"
+)
+
+var (
+	allNamespaces bool
+	// The namespace directory of each entry when collecting from every Trident install
+	logNamespacePrefix string
+)
+
+func init() {
+	logsCmd.Flags().BoolVar(&allNamespaces, "all-namespaces", false, "Collect the logs of every Trident install in the cluster, with the entries of each in a directory named for its namespace.")
+}
+
+// discoverAllNamespaces finds the Kubernetes CLI for collecting from every Trident install,
+// since there is no single Trident namespace in which to find the Trident pod.
+func discoverAllNamespaces() error {
+
+	if TridentPodNamespace != "" {
+		return errors.New("--all-namespaces cannot be used with --namespace")
+	}
+	if err := discoverKubernetesCLI(); err != nil {
+		return err
+	}
+	InCluster = detectInCluster()
+	OperatingMode = ModeTunnel
+
+	return nil
+}
+
+// checkValidAllNamespaces checks that logs are collected from every Trident install only once.
+func checkValidAllNamespaces() error {
+	if allNamespaces && follow {
+		return errors.New("--all-namespaces cannot be used with --follow")
+	}
+	return nil
+}
+
+// listTridentNamespaces returns the running Trident controller pods in every namespace, keyed by
+// namespace.
+func listTridentNamespaces() (map[string]string, error) {
+
+	var pods k8s.PodList
+	if err := getKubernetesObjects(&pods, "get", "pod", "--all-namespaces", "-l",
+		tridentWorkloadSelector(TridentCSILabelValue, TridentLegacyLabelValue), "-o=json",
+		"--field-selector=status.phase=Running"); err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, errors.New("could not find a Trident pod in any namespace")
+	}
+
+	controllerPods := make(map[string]string)
+	for _, pod := range pods.Items {
+		controllerPods[pod.Namespace] = pod.Name
+	}
+	return controllerPods, nil
+}
+
+// getAllNamespaceLogs collects the logs of each Trident install in turn, writing the entries of
+// each, including its version in an archive, under its namespace.
+func getAllNamespaceLogs() error {
+
+	controllerPods, err := listTridentNamespaces()
+	if err != nil {
+		return err
+	}
+
+	namespaces := make([]string, 0, len(controllerPods))
+	for namespace := range controllerPods {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	savedNamespace, savedPodName := TridentPodNamespace, TridentPodName
+	defer func() {
+		TridentPodNamespace, TridentPodName, logNamespacePrefix = savedNamespace, savedPodName, ""
+	}()
+
+	var collectErr error
+	for _, namespace := range namespaces {
+		TridentPodNamespace, TridentPodName = namespace, controllerPods[namespace]
+		logNamespacePrefix = namespace + "/"
+		if err = getNamespaceLogs(); err != nil && collectErr == nil {
+			collectErr = fmt.Errorf("could not collect the logs in namespace %s; %v", namespace, err)
+		}
+		// The version is read from the controller of each install, so it cannot wait for the archive
+		if archive && collectionContext.Err() == nil {
+			writeClusterState(logNameVersion, getVersionInfo)
+		}
+	}
+
+	return collectErr
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const listControllerPodsCommand = "get pod --all-namespaces -l app in (controller.csi.trident.netapp.io,trident.netapp.io) " +
+	"-o=json --field-selector=status.phase=Running"
+
+func TestListTridentNamespaces(t *testing.T) {
+
+	runner := &fakeCommandRunner{outputs: map[string]string{listControllerPodsCommand: `{"items": [
+		{"metadata": {"name": "trident-csi-6b8f9", "namespace": "trident"}},
+		{"metadata": {"name": "trident-csi-77d4c", "namespace": "trident-test"}}
+	]}`}}
+	defer useFakeCommandRunner(runner)()
+
+	controllerPods, err := listTridentNamespaces()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"trident": "trident-csi-6b8f9", "trident-test": "trident-csi-77d4c"},
+		controllerPods)
+
+	runner.outputs[listControllerPodsCommand] = `{"items": []}`
+	_, err = listTridentNamespaces()
+	assert.EqualError(t, err, "could not find a Trident pod in any namespace")
+}
+
+func TestWriteLogEntryNamespacePrefix(t *testing.T) {
+
+	defer useFakeCommandRunner(&fakeCommandRunner{})()
+	defer func() { logNamespacePrefix = "" }()
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	logNamespacePrefix = "trident-test/"
+	assert.Nil(t, writeLogEntry("trident", []byte("level=info msg=\"Started.\"\n"), archiveEntryFlags{}))
+	assert.Equal(t, "trident-test/trident log:\nlevel=info msg=\"Started.\"\n\n", console.String())
+}
+
+func TestGetAllNamespaceLogs(t *testing.T) {
+
+	runner := &fakeCommandRunner{outputs: map[string]string{
+		listControllerPodsCommand: `{"items": [
+			{"metadata": {"name": "trident-csi-6b8f9", "namespace": "trident"}},
+			{"metadata": {"name": "trident-csi-77d4c", "namespace": "trident-test"}}
+		]}`,
+		"logs trident-csi-6b8f9 -n trident -c trident-main --previous=false":      "level=info msg=\"Started.\"\n",
+		"describe pod trident-csi-6b8f9 -n trident":                               "Name: trident-csi-6b8f9\n",
+		"logs trident-csi-77d4c -n trident-test -c trident-main --previous=false": "level=info msg=\"Started.\"\n",
+		"describe pod trident-csi-77d4c -n trident-test":                          "Name: trident-csi-77d4c\n",
+	}}
+	defer useFakeCommandRunner(runner)()
+
+	savedLogType, savedDescribePods, savedPodName := logType, describePods, TridentPodName
+	defer func() { logType, describePods, TridentPodName = savedLogType, savedDescribePods, savedPodName }()
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	logType, describePods, TridentPodName = logTypeTrident, true, "trident-csi-saved"
+	assert.Nil(t, getAllNamespaceLogs())
+	assert.Equal(t, []string{
+		listControllerPodsCommand,
+		"logs trident-csi-6b8f9 -n trident -c trident-main --previous=false",
+		"describe pod trident-csi-6b8f9 -n trident",
+		"logs trident-csi-77d4c -n trident-test -c trident-main --previous=false",
+		"describe pod trident-csi-77d4c -n trident-test",
+	}, runner.commands)
+	assert.True(t, logErrors.empty())
+	assert.Equal(t, ""+
+		"trident/trident-controller log:\nlevel=info msg=\"Started.\"\n\n"+
+		"trident/describe-trident-csi-6b8f9 log:\nName: trident-csi-6b8f9\n\n"+
+		"trident-test/trident-controller log:\nlevel=info msg=\"Started.\"\n\n"+
+		"trident-test/describe-trident-csi-77d4c log:\nName: trident-csi-77d4c\n\n", console.String())
+	assert.Equal(t, "trident", TridentPodNamespace)
+	assert.Equal(t, "trident-csi-saved", TridentPodName)
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// FormatNDJSON writes each console log line as a JSON object on its own line.
+const FormatNDJSON = "ndjson"
+
+// ndjsonSources are the containers of the collected logs, keyed by log name, so that each line
+// written as JSON names its source.
+var ndjsonSources = make(map[string]collectionResult)
+
+// ndjsonLine is a log line written as JSON, with the time of its --timestamps prefix, if any.
+type ndjsonLine struct {
+	Log       string     `json:"log"`
+	Pod       string     `json:"pod"`
+	Container string     `json:"container"`
+	Node      string     `json:"node"`
+	Message   string     `json:"message"`
+	Timestamp *time.Time `json:"ts"`
+}
+
+// checkValidNDJSON checks that log lines are only written as JSON to the console.
+func checkValidNDJSON() error {
+	if OutputFormat == FormatNDJSON && (archive || estimate || logsOutDir != "" || follow) {
+		return errors.New("ndjson output is only supported in console mode, without --follow or --out-dir")
+	}
+	return nil
+}
+
+// writeNDJSONLines writes each line of a log as a JSON object.  A line without a timestamp, such
+// as one continuing a stack trace, has a null time.
+func writeNDJSONLines(w io.Writer, logName string, logEntry []byte) error {
+
+	source := ndjsonSources[logName]
+	encoder := json.NewEncoder(w)
+
+	for _, line := range strings.SplitAfter(string(logEntry), "\n") {
+		if line == "" {
+			continue
+		}
+		jsonLine := ndjsonLine{
+			Log:       logNamespacePrefix + logName,
+			Pod:       source.Pod,
+			Container: source.Container,
+			Node:      source.Node,
+			Message:   strings.TrimSuffix(line, "\n"),
+		}
+		if timestamps {
+			if timestamp, message := splitLogTimestamp(jsonLine.Message); !timestamp.IsZero() {
+				jsonLine.Timestamp, jsonLine.Message = &timestamp, message
+			}
+		}
+		if err := encoder.Encode(jsonLine); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
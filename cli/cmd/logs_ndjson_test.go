@@ -0,0 +1,30 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteNDJSONLines(t *testing.T) {
+
+	savedTimestamps, savedSources := timestamps, ndjsonSources
+	defer func() { timestamps, ndjsonSources = savedTimestamps, savedSources }()
+
+	timestamps = true
+	ndjsonSources = map[string]collectionResult{
+		"trident-node-worker-1": {Name: "trident-node-worker-1", Pod: "trident-csi-a", Container: "trident-main",
+			Node: "worker-1"},
+	}
+
+	var output bytes.Buffer
+	logEntry := []byte("2020-01-20T15:04:05.123Z level=error msg=\"Mount failed.\"\ngoroutine 1 [running]:\n")
+	assert.Nil(t, writeNDJSONLines(&output, "trident-node-worker-1", logEntry))
+	assert.Equal(t, `{"log":"trident-node-worker-1","pod":"trident-csi-a","container":"trident-main",`+
+		`"node":"worker-1","message":"level=error msg=\"Mount failed.\"","ts":"2020-01-20T15:04:05.123Z"}`+"\n"+
+		`{"log":"trident-node-worker-1","pod":"trident-csi-a","container":"trident-main",`+
+		`"node":"worker-1","message":"goroutine 1 [running]:","ts":null}`+"\n", output.String())
+}
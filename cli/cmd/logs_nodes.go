@@ -0,0 +1,159 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/netapp/trident/config"
+)
+
+var (
+	multipathState  bool
+	nodeDiagnostics bool
+)
+
+func init() {
+	logsCmd.Flags().BoolVar(&multipathState, "multipath", false, "Also collect the multipath state of each node host.")
+	logsCmd.Flags().BoolVar(&nodeDiagnostics, "node-diagnostics", false, "Also collect the storage diagnostics of each node host, including its multipath state, iSCSI sessions, NVMe subsystems, block devices and mounts.")
+}
+
+// nodeCommand is a command run on each node host, through the Trident node pod, whose output is
+// collected as an archive entry.
+type nodeCommand struct {
+	entryPrefix string
+	commands    [][]string
+	// A tool that may not be installed on every host, whose absence is noted rather than an error
+	optionalTool string
+}
+
+var (
+	multipathCommand = nodeCommand{entryPrefix: "multipath", commands: [][]string{{"multipath", "-ll"}}}
+	iscsiCommand     = nodeCommand{entryPrefix: "iscsi", commands: [][]string{
+		{"iscsiadm", "-m", "session"},
+		{"iscsiadm", "-m", "node"},
+	}}
+	blockDevicesCommand = nodeCommand{entryPrefix: "blockdevices", commands: [][]string{
+		{"lsblk", "-O"},
+		{"mount"},
+		{"df", "-h"},
+	}}
+	nvmeCommand = nodeCommand{entryPrefix: "nvme", optionalTool: "nvme", commands: [][]string{
+		{"nvme", "list"},
+		{"nvme", "list-subsys"},
+		{"sh", "-c", "grep -H . /sys/class/nvme/nvme*/transport /sys/class/nvme/nvme*/address " +
+			"/sys/class/nvme/nvme*/state 2>/dev/null || echo 'No NVMe controllers found'"},
+	}}
+)
+
+// getNodeDiagnostics collects the output of the selected node host commands from each Trident
+// node pod.
+func getNodeDiagnostics() {
+
+	var nodeCommands []nodeCommand
+	if multipathState || nodeDiagnostics {
+		nodeCommands = append(nodeCommands, multipathCommand)
+	}
+	if nodeDiagnostics {
+		nodeCommands = append(nodeCommands, iscsiCommand, blockDevicesCommand, nvmeCommand)
+	}
+	if len(nodeCommands) == 0 {
+		return
+	}
+
+	tridentNodes, err := selectedTridentNodes()
+	if err != nil {
+		logErrors.add(fmt.Sprintf("could not list nodes for diagnostics; %v", err))
+		return
+	}
+
+	nodeNames := make([]string, 0, len(tridentNodes))
+	for nodeName := range tridentNodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	for _, diagnostic := range nodeCommands {
+		for _, nodeName := range nodeNames {
+			writeNodeCommandOutput(diagnostic, nodeName, tridentNodes[nodeName])
+		}
+	}
+}
+
+// selectedTridentNodes returns the Trident node pods specified with --node, or those matching
+// --selector and --node-pattern, keyed by node name.
+func selectedTridentNodes() (map[string]string, error) {
+
+	if len(nodes) == 0 {
+		return listSelectedTridentNodes()
+	}
+
+	tridentNodes := make(map[string]string)
+	var missingNodes []string
+	for _, nodeName := range nodes {
+		pod, err := getTridentNode(nodeName, TridentPodNamespace)
+		if err != nil {
+			missingNodes = append(missingNodes, nodeName)
+			continue
+		}
+		tridentNodes[nodeName] = pod
+	}
+	if len(missingNodes) > 0 {
+		logErrors.add(fmt.Sprintf("could not find the Trident node pods on node(s) %s",
+			strings.Join(missingNodes, ", ")))
+	}
+
+	return tridentNodes, nil
+}
+
+// writeNodeCommandOutput runs the commands of a node command in a Trident node pod and writes
+// their combined output as one entry.  A command that fails, such as one not present on the
+// host, is noted in the entry and the log errors, and the remaining commands are still run.  If
+// an optional tool is not installed, that is noted in the entry and its commands are skipped.
+func writeNodeCommandOutput(diagnostic nodeCommand, nodeName, pod string) {
+
+	logName := diagnostic.entryPrefix + "-" + nodeName
+
+	var commandOutput bytes.Buffer
+	for _, command := range diagnostic.commands {
+		if len(diagnostic.commands) > 1 {
+			fmt.Fprintf(&commandOutput, "# %s\n", strings.Join(command, " "))
+		}
+		output, err := execInPod(pod, config.ContainerTrident, command...)
+		if err != nil && command[0] == diagnostic.optionalTool && isCommandNotFound(err) {
+			fmt.Fprintf(&commandOutput, "# %s is not installed on node %s\n", diagnostic.optionalTool, nodeName)
+			break
+		}
+		if err != nil {
+			failure := fmt.Sprintf("could not run %s on node %s; %v", strings.Join(command, " "), nodeName, err)
+			logErrors.add(failure)
+			fmt.Fprintf(&commandOutput, "# %s\n", failure)
+			continue
+		}
+		commandOutput.Write(output)
+	}
+
+	if err := writeLogEntry(logName, commandOutput.Bytes(), archiveEntryFlags{}); err != nil {
+		writeError := fmt.Sprintf("could not write log %s; %v", logName, err)
+		logErrors.add(writeError)
+	}
+}
+
+// isCommandNotFound reports whether a command run in a pod failed because it is not installed.
+func isCommandNotFound(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "executable file not found") || strings.Contains(message, "command not found") ||
+		strings.Contains(message, "no such file or directory")
+}
+
+// execInPod runs a command in a container of a pod in the Trident namespace.
+func execInPod(pod, container string, command ...string) ([]byte, error) {
+
+	execCommand := append([]string{"exec", pod, "-n", TridentPodNamespace, "-c", container, "--"}, command...)
+	printInvokedCommand(KubernetesCLI, execCommand)
+
+	return cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(execCommand...)...)
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteNodeCommandOutput(t *testing.T) {
+
+	runner := &fakeCommandRunner{
+		outputs: map[string]string{
+			"exec trident-csi-a -n trident -c trident-main -- lsblk -O": "NAME MAJ:MIN\nsda  8:0\n",
+			"exec trident-csi-a -n trident -c trident-main -- df -h":    "Filesystem Size\n/dev/sda1  20G\n",
+		},
+		failures: map[string]string{
+			"exec trident-csi-a -n trident -c trident-main -- mount": "permission denied",
+		},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	writeNodeCommandOutput(blockDevicesCommand, "worker-1", "trident-csi-a")
+	assert.Equal(t, "blockdevices-worker-1 log:\n"+
+		"# lsblk -O\nNAME MAJ:MIN\nsda  8:0\n"+
+		"# mount\n# could not run mount on node worker-1; permission denied\n"+
+		"# df -h\nFilesystem Size\n/dev/sda1  20G\n\n", console.String())
+	assert.Equal(t, "could not run mount on node worker-1; permission denied", logErrors.String())
+}
+
+func TestWriteNodeCommandOutputMissingTool(t *testing.T) {
+
+	runner := &fakeCommandRunner{
+		failures: map[string]string{
+			"exec trident-csi-a -n trident -c trident-main -- nvme list": "OCI runtime exec failed: exec failed: " +
+				"container_linux.go:349: starting container process caused \"exec: \\\"nvme\\\": executable file " +
+				"not found in $PATH\": unknown",
+		},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	writeNodeCommandOutput(nvmeCommand, "worker-1", "trident-csi-a")
+	assert.Equal(t, "nvme-worker-1 log:\n# nvme list\n# nvme is not installed on node worker-1\n\n", console.String())
+	assert.True(t, logErrors.empty())
+}
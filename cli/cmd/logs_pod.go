@@ -0,0 +1,91 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	k8s "k8s.io/api/core/v1"
+
+	"github.com/netapp/trident/config"
+)
+
+var logsPodName string
+
+func init() {
+	logsCmd.Flags().StringVar(&logsPodName, "pod", "", "Collect the logs of this Trident pod in the Trident namespace, such as a node pod, without finding it by node.")
+}
+
+// checkValidPod checks that a pod named with --pod is not also to be found by node or owner.
+func checkValidPod() error {
+	if logsPodName == "" {
+		return nil
+	}
+	if len(nodes) > 0 || nodePattern != "" || len(excludedNodes) > 0 || ownedBy != "" || logsPVName != "" ||
+		allNamespaces {
+		return errors.New("--pod cannot be used with --node, --node-pattern, --exclude-node, --owned-by, --pv " +
+			"or --all-namespaces")
+	}
+	return nil
+}
+
+// getNamedPodLogs collects the logs of the Trident pod specified with --pod, named as those of
+// the controller or of its node would be, along with its sidecars if requested.
+func getNamedPodLogs() error {
+
+	var pod k8s.Pod
+	if err := getKubernetesObjects(&pod, "get", "pod", logsPodName, "-n", TridentPodNamespace, "-o=json"); err != nil {
+		return fmt.Errorf("could not get pod %s; %v", logsPodName, err)
+	}
+
+	var logName string
+	switch pod.Labels[TridentCSILabelKey] {
+	case TridentCSILabelValue, TridentLegacyLabelValue:
+		logName = logNameTrident
+	case TridentNodeLabelValue:
+		logName = "trident-node-" + pod.Spec.NodeName
+	default:
+		return fmt.Errorf("pod %s in the %s namespace is not a Trident pod", logsPodName, TridentPodNamespace)
+	}
+
+	container, err := selectPodContainer(pod.Name, config.ContainerTrident)
+	if err != nil {
+		return err
+	}
+
+	prevValues := []bool{false}
+	if previous {
+		prevValues = append(prevValues, true)
+	}
+
+	for _, prev := range prevValues {
+		podLogName := logName
+		if prev {
+			podLogName += "-previous"
+		}
+
+		// A previous log is often missing, so only a failure to get the current one is an error
+		if _, collectErr := collectContainerLogs(containerLogName(podLogName, container), pod.Name, container,
+			pod.Spec.NodeName, prev); !prev {
+			err = collectErr
+		}
+
+		if sidecars {
+			var podSidecars []string
+			for _, podContainer := range pod.Spec.Containers {
+				if podContainer.Name != config.ContainerTrident {
+					podSidecars = append(podSidecars, podContainer.Name)
+				}
+			}
+			for _, sidecar := range selectSidecars(podSidecars) {
+				collectContainerLogs(podLogName+"-sidecar-"+sidecar, pod.Name, sidecar, pod.Spec.NodeName, prev)
+			}
+			if initErr := collectInitContainerLogs(podLogName, pod.Name, pod.Spec.NodeName, prev); initErr != nil {
+				return initErr
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNamedPodLogs(t *testing.T) {
+
+	nodePodJSON := `{"metadata": {"name": "trident-csi-xyz", "labels": {"app": "node.csi.trident.netapp.io"}},
+		"spec": {"nodeName": "worker-1", "containers": [{"name": "trident-main"}, {"name": "driver-registrar"}]}}`
+	runner := &fakeCommandRunner{
+		outputs: map[string]string{
+			"get pod trident-csi-xyz -n trident -o=json":                       nodePodJSON,
+			"get pod nginx -n trident -o=json":                                 `{"metadata": {"name": "nginx"}}`,
+			"logs trident-csi-xyz -n trident -c trident-main --previous=false": "level=info msg=\"Node started.\"\n",
+		},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	savedPodName := logsPodName
+	defer func() { logsPodName = savedPodName }()
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	logsPodName = "trident-csi-xyz"
+	assert.Nil(t, getNamedPodLogs())
+	assert.Equal(t, "trident-node-worker-1 log:\nlevel=info msg=\"Node started.\"\n\n", console.String())
+	assert.Equal(t, []collectionResult{{Name: "trident-node-worker-1", Namespace: "trident", Pod: "trident-csi-xyz",
+		Container: "trident-main", Node: "worker-1", Bytes: 31}}, collectionResults)
+
+	logsPodName = "nginx"
+	assert.EqualError(t, getNamedPodLogs(), "pod nginx in the trident namespace is not a Trident pod")
+}
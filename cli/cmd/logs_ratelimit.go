@@ -0,0 +1,136 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+var (
+	rateLimit string
+	// The limiter shared by every container log being read, if --rate-limit was specified
+	logsByteLimiter *byteRateLimiter
+)
+
+func init() {
+	logsCmd.Flags().StringVar(&rateLimit, "rate-limit", "", "The maximum number of bytes of container logs read per second, e.g. 5MB, across all the logs collected at once. Unlimited if not specified. Unlike --rate, which spaces out the log requests, this slows down the transfer of each log.")
+}
+
+// checkValidRateLimit checks the --rate-limit option and creates its limiter.
+func checkValidRateLimit() error {
+
+	if rateLimit == "" {
+		return nil
+	}
+	if follow {
+		return errors.New("--rate-limit cannot be used with --follow")
+	}
+
+	bytesPerSecond, err := humanize.ParseBytes(rateLimit)
+	if err != nil || bytesPerSecond == 0 {
+		return fmt.Errorf("%s is not a valid --rate-limit", rateLimit)
+	}
+	logsByteLimiter = newByteRateLimiter(int64(bytesPerSecond))
+
+	return nil
+}
+
+// byteRateLimiter paces the bytes read by any number of readers so that, together, they do not
+// exceed a rate.
+type byteRateLimiter struct {
+	mutex          sync.Mutex
+	bytesPerSecond int64
+	next           time.Time
+}
+
+func newByteRateLimiter(bytesPerSecond int64) *byteRateLimiter {
+	return &byteRateLimiter{bytesPerSecond: bytesPerSecond}
+}
+
+// reserve accounts for bytes about to be read at a time, returning how long to wait first.
+func (l *byteRateLimiter) reserve(n int, now time.Time) time.Duration {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Time not spent reading is not saved up for a later burst
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSecond))
+
+	return delay
+}
+
+// chunkSize returns the most bytes read at once, a tenth of a second at the rate, so that no read
+// takes a much larger share of the rate than the others.
+func (l *byteRateLimiter) chunkSize() int {
+	if chunk := l.bytesPerSecond / 10; chunk > 0 {
+		return int(chunk)
+	}
+	return 1
+}
+
+// throttledReader reads from a reader no faster than the rate of its limiter.
+type throttledReader struct {
+	reader  io.Reader
+	limiter *byteRateLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if chunk := r.limiter.chunkSize(); len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		time.Sleep(r.limiter.reserve(n, time.Now()))
+	}
+	return n, err
+}
+
+// throttledWriter accepts writes no faster than the rate of its limiter, which slows down the
+// command writing to it through a pipe.
+type throttledWriter struct {
+	writer  io.Writer
+	limiter *byteRateLimiter
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		chunk := p[written:]
+		if chunkSize := w.limiter.chunkSize(); len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		time.Sleep(w.limiter.reserve(len(chunk), time.Now()))
+		n, err := w.writer.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// throttleLogsReader returns a reader of container logs limited to --rate-limit, if specified.
+func throttleLogsReader(r io.Reader) io.Reader {
+	if logsByteLimiter == nil {
+		return r
+	}
+	return &throttledReader{reader: r, limiter: logsByteLimiter}
+}
+
+// throttleLogsWriter returns a writer of container logs limited to --rate-limit, if specified.
+func throttleLogsWriter(w io.Writer) io.Writer {
+	if logsByteLimiter == nil {
+		return w
+	}
+	return &throttledWriter{writer: w, limiter: logsByteLimiter}
+}
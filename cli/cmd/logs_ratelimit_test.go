@@ -0,0 +1,58 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteRateLimiterReserve(t *testing.T) {
+
+	limiter := newByteRateLimiter(1000)
+	start := time.Now()
+
+	assert.Equal(t, time.Duration(0), limiter.reserve(500, start), "the first read should not wait")
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve(1000, start), "a read should wait for the one before")
+	assert.Equal(t, 1000*time.Millisecond, limiter.reserve(100, start.Add(500*time.Millisecond)))
+
+	// An idle limiter does not allow a burst afterward
+	assert.Equal(t, time.Duration(0), limiter.reserve(100, start.Add(time.Minute)))
+	assert.Equal(t, 100*time.Millisecond, limiter.reserve(100, start.Add(time.Minute)))
+}
+
+func TestThrottledReaderAndWriter(t *testing.T) {
+
+	content := strings.Repeat("log line\n", 100)
+	limiter := newByteRateLimiter(1 << 30)
+
+	read, err := ioutil.ReadAll(&throttledReader{reader: strings.NewReader(content), limiter: limiter})
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(read))
+
+	var written bytes.Buffer
+	n, err := (&throttledWriter{writer: &written, limiter: newByteRateLimiter(100)}).Write([]byte("0123456789abc"))
+	assert.Nil(t, err)
+	assert.Equal(t, 13, n)
+	assert.Equal(t, "0123456789abc", written.String())
+}
+
+func TestCheckValidRateLimit(t *testing.T) {
+
+	defer func() { rateLimit, logsByteLimiter, follow = "", nil, false }()
+
+	rateLimit = "5MB"
+	assert.Nil(t, checkValidRateLimit())
+	assert.Equal(t, int64(5000000), logsByteLimiter.bytesPerSecond)
+
+	rateLimit = "none"
+	assert.EqualError(t, checkValidRateLimit(), "none is not a valid --rate-limit")
+
+	rateLimit, follow = "1MB", true
+	assert.EqualError(t, checkValidRateLimit(), "--rate-limit cannot be used with --follow")
+}
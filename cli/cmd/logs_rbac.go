@@ -0,0 +1,68 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+var skipRBACCheck bool
+
+func init() {
+	logsCmd.Flags().BoolVar(&skipRBACCheck, "skip-rbac-check", false, "Skip checking that the Kubernetes permissions needed to collect the logs are granted, e.g. where SelfSubjectAccessReview is disabled.")
+}
+
+// rbacPermission is a Kubernetes verb on a resource that log collection needs.
+type rbacPermission struct {
+	verb     string
+	resource string
+}
+
+func (p rbacPermission) String() string {
+	return p.verb + " " + p.resource
+}
+
+// requiredPermissions returns the permissions needed by the selected collection.
+func requiredPermissions() []rbacPermission {
+
+	permissions := []rbacPermission{{"get", "pods/log"}}
+	if nodeDiagnostics || multipathState {
+		permissions = append(permissions, rbacPermission{"create", "pods/exec"})
+	}
+	return permissions
+}
+
+// checkRBACPermissions asks the Kubernetes API whether each permission needed by the selected
+// collection is granted, so that a collection does not fail halfway for lack of one.
+func checkRBACPermissions() error {
+
+	namespaceArgs := []string{"-n", TridentPodNamespace}
+	namespaceName := "namespace " + TridentPodNamespace
+	if allNamespaces {
+		namespaceArgs, namespaceName = []string{"--all-namespaces"}, "every namespace"
+	}
+
+	var missing []string
+	for _, permission := range requiredPermissions() {
+		canICommand := append([]string{"auth", "can-i", permission.verb, permission.resource}, namespaceArgs...)
+		printInvokedCommand(KubernetesCLI, canICommand)
+
+		// The CLI answers "no" with a failure exit code, so only an unanswered question is an error
+		output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(canICommand...)...)
+		switch strings.TrimSpace(string(output)) {
+		case "yes":
+		case "no":
+			missing = append(missing, permission.String())
+		default:
+			return fmt.Errorf("could not check whether %s is permitted; %v. Use --skip-rbac-check to skip "+
+				"this check", permission, err)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing the Kubernetes permissions to %s in %s. Ask a cluster administrator to "+
+			"grant them, or use --skip-rbac-check to try anyway", strings.Join(missing, ", "), namespaceName)
+	}
+	return nil
+}
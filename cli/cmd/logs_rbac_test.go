@@ -0,0 +1,33 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRBACPermissions(t *testing.T) {
+
+	runner := &fakeCommandRunner{outputs: map[string]string{
+		"auth can-i get pods/log -n trident":     "yes\n",
+		"auth can-i create pods/exec -n trident": "no\n",
+	}}
+	defer useFakeCommandRunner(runner)()
+
+	savedDiagnostics := nodeDiagnostics
+	defer func() { nodeDiagnostics = savedDiagnostics }()
+
+	nodeDiagnostics = false
+	assert.Nil(t, checkRBACPermissions())
+
+	nodeDiagnostics = true
+	assert.EqualError(t, checkRBACPermissions(), "missing the Kubernetes permissions to create pods/exec in "+
+		"namespace trident. Ask a cluster administrator to grant them, or use --skip-rbac-check to try anyway")
+
+	runner.outputs = nil
+	runner.failures = map[string]string{"auth can-i get pods/log -n trident": "selfsubjectaccessreviews is forbidden"}
+	assert.EqualError(t, checkRBACPermissions(), "could not check whether get pods/log is permitted; "+
+		"selfsubjectaccessreviews is forbidden. Use --skip-rbac-check to skip this check")
+}
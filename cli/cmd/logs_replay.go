@@ -0,0 +1,314 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	replayFilenameFormat = "replay-2006-01-02T15-04-05-MST"
+	logNameMerged        = "merged.log"
+)
+
+var (
+	replayGrep    string
+	replayLevel   string
+	replayMerge   bool
+	replayArchive bool
+	replayFormat  string
+)
+
+// logLevelSeverity orders the normalized log levels returned by parseLogLevel.
+var logLevelSeverity = map[string]int{"debug": 0, "info": 1, "warning": 2, "error": 3, "fatal": 4, "panic": 5}
+
+// archiveFile is one entry read from a support archive.
+type archiveFile struct {
+	name    string
+	content []byte
+}
+
+func init() {
+	logsCmd.AddCommand(logsReplayCmd)
+	logsReplayCmd.Flags().StringVar(&replayGrep, "grep", "", "Keep only the log lines matching this regular expression.")
+	logsReplayCmd.Flags().StringVar(&replayLevel, "level", "", "Keep only the log lines at or above this level. One of debug|info|warning|error|fatal|panic")
+	logsReplayCmd.Flags().BoolVar(&replayMerge, "merge", false, "Merge the lines of all logs into one log, ordered by time.")
+	logsReplayCmd.Flags().BoolVarP(&replayArchive, "archive", "a", false, "Write the filtered logs to a new support archive instead of the console.")
+	logsReplayCmd.Flags().StringVar(&replayFormat, "format", archiveFormatZip, "Format of the new support archive. One of zip|tgz")
+}
+
+var logsReplayCmd = &cobra.Command{
+	Use:   "replay <archive>",
+	Short: "Print or re-archive the logs in a support archive",
+	Long:  "Apply filters to the logs in an existing support archive, in zip or tar.gz format",
+	Args:  cobra.ExactArgs(1),
+	// Replaying an archive needs no access to Trident
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		var grepRegex *regexp.Regexp
+		if replayGrep != "" {
+			var err error
+			if grepRegex, err = regexp.Compile(replayGrep); err != nil {
+				return fmt.Errorf("%s is not a valid regular expression; %v", replayGrep, err)
+			}
+		}
+		if _, ok := logLevelSeverity[replayLevel]; replayLevel != "" && !ok {
+			return fmt.Errorf("%s is not a valid log level", replayLevel)
+		}
+		if replayFormat != archiveFormatZip && replayFormat != archiveFormatTgz {
+			return fmt.Errorf("%s is not a valid archive format", replayFormat)
+		}
+
+		files, err := readArchiveEntries(args[0])
+		if err != nil {
+			return err
+		}
+
+		return replayLogs(replayArchiveFiles(files, grepRegex, replayLevel, replayMerge))
+	},
+}
+
+// readArchiveEntries returns the entries of a support archive in zip or tar.gz format.
+func readArchiveEntries(archiveName string) ([]archiveFile, error) {
+
+	archiveBytes, err := ioutil.ReadFile(archiveName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read archive %s; %v", archiveName, err)
+	}
+
+	var files []archiveFile
+	if bytes.HasPrefix(archiveBytes, []byte{0x1f, 0x8b}) {
+		files, err = readTarGzEntries(archiveBytes)
+	} else {
+		files, err = readZipEntries(archiveBytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read archive %s; %v", archiveName, err)
+	}
+
+	return files, nil
+}
+
+func readZipEntries(archiveBytes []byte) ([]archiveFile, error) {
+
+	reader, err := zip.NewReader(bytes.NewReader(archiveBytes), int64(len(archiveBytes)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]archiveFile, 0, len(reader.File))
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		entry, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(entry)
+		entry.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, archiveFile{name: file.Name, content: content})
+	}
+
+	return files, nil
+}
+
+func readTarGzEntries(archiveBytes []byte) ([]archiveFile, error) {
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archiveBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	files := make([]archiveFile, 0)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, archiveFile{name: header.Name, content: content})
+	}
+}
+
+// replayArchiveFiles applies the replay filters to the logs of a support archive, omitting the
+// manifest, the index, and any log left empty.  If merging, the remaining lines of all logs are
+// combined into one log ordered by time, each prefixed with the name of its log.
+func replayArchiveFiles(
+	files []archiveFile, grepRegex *regexp.Regexp, minLevel string, merge bool,
+) []archiveFile {
+
+	replayed := make([]archiveFile, 0, len(files))
+	var mergedLines []timedLogLine
+
+	for _, file := range files {
+		if file.name == archiveManifestName || file.name == archiveIndexName {
+			continue
+		}
+		content := filterReplayLines(file.content, grepRegex, minLevel)
+		if len(content) == 0 {
+			continue
+		}
+		if merge {
+			mergedLines = append(mergedLines, timeLogLines(file.name, content)...)
+		} else {
+			replayed = append(replayed, archiveFile{name: file.name, content: content})
+		}
+	}
+
+	if merge && len(mergedLines) > 0 {
+		replayed = append(replayed, archiveFile{name: logNameMerged, content: mergeTimedLogLines(mergedLines)})
+	}
+
+	return replayed
+}
+
+// filterReplayLines keeps the lines of a log that match the regular expression and are at or
+// above the minimum level, if set.  Lines without a level share the fate of the line before them.
+func filterReplayLines(logEntry []byte, grepRegex *regexp.Regexp, minLevel string) []byte {
+
+	if minLevel != "" {
+		logEntry = filterMinLogLevel(logEntry, minLevel)
+	}
+	if grepRegex != nil {
+		logEntry = grepLogLines(logEntry, grepRegex, false)
+	}
+	return logEntry
+}
+
+// timedLogLine is a log line prefixed with the name of its log, along with its time.
+type timedLogLine struct {
+	time time.Time
+	line string
+}
+
+// timeLogLines prefixes each line of a log with the log name and records its time.  Lines
+// without a time share the time of the line before them.
+func timeLogLines(logName string, logEntry []byte) []timedLogLine {
+
+	var timedLines []timedLogLine
+	var lineTime time.Time
+
+	scanner := bufio.NewScanner(bytes.NewReader(logEntry))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if parsedTime := parseLogLineTime(line); !parsedTime.IsZero() {
+			lineTime = parsedTime
+		}
+		timedLines = append(timedLines, timedLogLine{time: lineTime, line: "[" + logName + "] " + line + "\n"})
+	}
+
+	return timedLines
+}
+
+// parseLogLineTime returns the time of a log line from its timestamp prefix or its time field,
+// or the zero time if it has neither.
+func parseLogLineTime(line string) time.Time {
+
+	if timestamp, _ := splitLogTimestamp(line); !timestamp.IsZero() {
+		return timestamp
+	}
+
+	if fieldTime, err := time.Parse(time.RFC3339Nano, parseLogLineFields(line)["time"]); err == nil {
+		return fieldTime
+	}
+
+	return time.Time{}
+}
+
+// replayLogs writes the replayed logs to the console, or to a new support archive.
+func replayLogs(files []archiveFile) error {
+
+	if len(files) == 0 {
+		return errors.New("no log lines matched the replay filters")
+	}
+
+	if replayArchive {
+		archiveName := time.Now().Format(replayFilenameFormat) + archiveExtension(replayFormat)
+		if err := writeReplayArchive(archiveName, replayFormat, files); err != nil {
+			return err
+		}
+		if absArchiveName, err := filepath.Abs(archiveName); err == nil {
+			archiveName = absArchiveName
+		}
+		printArchiveResult("Replayed archive written to %s.\n", archiveName)
+		return nil
+	}
+
+	for _, file := range files {
+		flags := archiveEntryFlags{Filtered: true, LineCount: countLines(file.content)}
+		if err := writeTransformedLogEntry(file.name, file.content, flags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReplayArchive writes the replayed logs and their manifest to a new support archive.  An
+// archive that could not be completed is removed, rather than left behind looking valid.
+func writeReplayArchive(archiveName, format string, files []archiveFile) (err error) {
+
+	archiveFile, err := os.Create(archiveName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(archiveName)
+		}
+	}()
+
+	if archiveWriter, err = newArchiveWriter(format, archiveFile); err != nil {
+		_ = archiveFile.Close()
+		return err
+	}
+
+	for _, file := range files {
+		flags := archiveEntryFlags{Filtered: true, LineCount: countLines(file.content)}
+		if err = writeArchiveEntry(file.name, file.content, flags); err != nil {
+			_ = closeSupportArchive(archiveWriter, archiveFile.Close)
+			return err
+		}
+		printArchiveProgress("Wrote %s log to %s archive file.\n", file.name, archiveName)
+	}
+	if err = writeArchiveManifest(); err != nil {
+		_ = closeSupportArchive(archiveWriter, archiveFile.Close)
+		return err
+	}
+
+	if err = closeSupportArchive(archiveWriter, archiveFile.Close); err != nil {
+		return fmt.Errorf("could not finish the replayed archive %s; %v", archiveName, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	replayControllerLog = "time=\"2020-01-20T15:04:01Z\" level=info msg=\"Added backend.\"\n" +
+		"time=\"2020-01-20T15:04:03Z\" level=error msg=\"Could not create volume pvc-1.\"\n" +
+		"  details of the error\n"
+	replayNodeLog = "time=\"2020-01-20T15:04:02Z\" level=warning msg=\"Mount of pvc-1 is slow.\"\n" +
+		"time=\"2020-01-20T15:04:04Z\" level=debug msg=\"Staged volume pvc-2.\"\n"
+)
+
+func TestReadArchiveEntries(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "replay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var zipBuffer bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuffer)
+	entry, _ := zipWriter.Create(logNameTrident)
+	_, _ = entry.Write([]byte(replayControllerLog))
+	assert.Nil(t, zipWriter.Close())
+	zipName := filepath.Join(dir, "support.zip")
+	assert.Nil(t, ioutil.WriteFile(zipName, zipBuffer.Bytes(), 0644))
+
+	var tarBuffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&tarBuffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	assert.Nil(t, tarWriter.WriteHeader(&tar.Header{
+		Name: logNameNode, Mode: 0644, Size: int64(len(replayNodeLog)), Typeflag: tar.TypeReg}))
+	_, _ = tarWriter.Write([]byte(replayNodeLog))
+	assert.Nil(t, tarWriter.Close())
+	assert.Nil(t, gzipWriter.Close())
+	tarName := filepath.Join(dir, "support.tar.gz")
+	assert.Nil(t, ioutil.WriteFile(tarName, tarBuffer.Bytes(), 0644))
+
+	files, err := readArchiveEntries(zipName)
+	assert.Nil(t, err)
+	assert.Equal(t, []archiveFile{{name: logNameTrident, content: []byte(replayControllerLog)}}, files)
+
+	files, err = readArchiveEntries(tarName)
+	assert.Nil(t, err)
+	assert.Equal(t, []archiveFile{{name: logNameNode, content: []byte(replayNodeLog)}}, files)
+
+	_, err = readArchiveEntries(filepath.Join(dir, "missing.zip"))
+	assert.NotNil(t, err)
+}
+
+func TestReplayArchiveFiles(t *testing.T) {
+
+	files := []archiveFile{
+		{name: archiveManifestName, content: []byte("[]")},
+		{name: logNameTrident, content: []byte(replayControllerLog)},
+		{name: logNameNode, content: []byte(replayNodeLog)},
+	}
+
+	replayed := replayArchiveFiles(files, nil, "warning", false)
+	assert.Equal(t, []archiveFile{
+		{name: logNameTrident, content: []byte("time=\"2020-01-20T15:04:03Z\" level=error msg=\"Could not create volume pvc-1.\"\n" +
+			"  details of the error\n")},
+		{name: logNameNode, content: []byte("time=\"2020-01-20T15:04:02Z\" level=warning msg=\"Mount of pvc-1 is slow.\"\n")},
+	}, replayed)
+
+	replayed = replayArchiveFiles(files, regexp.MustCompile(`pvc-\d`), "", true)
+	assert.Equal(t, []archiveFile{{name: logNameMerged, content: []byte(
+		"[trident-node] time=\"2020-01-20T15:04:02Z\" level=warning msg=\"Mount of pvc-1 is slow.\"\n" +
+			"[trident-controller] time=\"2020-01-20T15:04:03Z\" level=error msg=\"Could not create volume pvc-1.\"\n" +
+			"[trident-node] time=\"2020-01-20T15:04:04Z\" level=debug msg=\"Staged volume pvc-2.\"\n")}}, replayed)
+
+	assert.Empty(t, replayArchiveFiles(files, regexp.MustCompile(`pvc-9`), "", false))
+}
+
+func TestWriteReplayArchive(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "replay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	savedManifest, savedQuiet := archiveManifest, logsQuiet
+	defer func() { archiveManifest, logsQuiet = savedManifest, savedQuiet }()
+	archiveManifest, logsQuiet = nil, true
+
+	files := []archiveFile{{name: logNameTrident, content: []byte(replayControllerLog)}}
+
+	tarName := filepath.Join(dir, "replay.tar.gz")
+	assert.Nil(t, writeReplayArchive(tarName, archiveFormatTgz, files))
+	replayed, err := readArchiveEntries(tarName)
+	assert.Nil(t, err)
+	if assert.Len(t, replayed, 2) {
+		assert.Equal(t, files[0], replayed[0])
+		assert.Equal(t, archiveManifestName, replayed[1].name)
+	}
+
+	// An archive that cannot be written is not left behind
+	badName := filepath.Join(dir, "replay.rar")
+	assert.EqualError(t, writeReplayArchive(badName, "rar", files), "rar is not a valid archive format")
+	_, err = os.Stat(badName)
+	assert.True(t, os.IsNotExist(err))
+}
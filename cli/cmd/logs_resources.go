@@ -0,0 +1,203 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	appsv1 "k8s.io/api/apps/v1"
+	k8s "k8s.io/api/core/v1"
+
+	frontendcsi "github.com/netapp/trident/frontend/csi"
+)
+
+const (
+	logNamePVs                   = "pvs"
+	logNamePVCs                  = "pvcs"
+	logNameStorageClassList      = "storageclasses"
+	logNameVolumeSnapshotClasses = "volumesnapshotclasses"
+	logNameDeployments           = "deployments"
+	logNameDaemonSets            = "daemonsets"
+	logNameNodes                 = "nodes"
+
+	// The annotation naming the provisioner of a PVC, set even before it is bound
+	storageProvisionerAnnotation = "volume.beta.kubernetes.io/storage-provisioner"
+)
+
+var k8sResources bool
+
+func init() {
+	logsCmd.Flags().BoolVar(&k8sResources, "k8s-resources", false, "In archive mode, also collect the Kubernetes resources related to Trident, such as its PVs, PVCs, storage and snapshot classes, workload specs, and nodes.")
+}
+
+// checkValidK8sResources checks that the Kubernetes resources are only collected into an archive.
+func checkValidK8sResources() error {
+	if k8sResources && (!archive || estimate) {
+		return errors.New("--k8s-resources is only supported in archive mode")
+	}
+	return nil
+}
+
+// getKubernetesResources writes the Kubernetes resources related to Trident to the archive.
+func getKubernetesResources() {
+	writeClusterState(logNamePVs, getTridentPVs)
+	writeClusterState(logNamePVCs, getTridentPVCs)
+	writeClusterState(logNameStorageClassList, func() ([]byte, error) {
+		return getClusterResources("storageclass")
+	})
+	writeClusterState(logNameVolumeSnapshotClasses, func() ([]byte, error) {
+		return getClusterResources("volumesnapshotclass")
+	})
+	writeClusterState(logNameDeployments, getTridentDeployments)
+	writeClusterState(logNameDaemonSets, getTridentDaemonSets)
+	writeClusterState(logNameNodes, func() ([]byte, error) {
+		return cliRunner.Run(KubernetesCLI, kubernetesCLIArgs("get", "nodes", "-o=wide")...)
+	})
+	writeNodeDescriptions()
+}
+
+// writeNodeDescriptions writes the description of each node hosting a Trident node pod, which
+// shows its conditions, such as DiskPressure, and its kernel version.
+func writeNodeDescriptions() {
+
+	tridentNodes, err := listTridentNodes(TridentPodNamespace, "")
+	if err != nil {
+		logErrors.add(fmt.Sprintf("could not list nodes to describe; %v", err))
+		return
+	}
+
+	nodeNames := make([]string, 0, len(tridentNodes))
+	for nodeName := range tridentNodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	for _, nodeName := range nodeNames {
+		nodeName := nodeName
+		writeClusterState("node-describe-"+nodeName, func() ([]byte, error) {
+			return cliRunner.Run(KubernetesCLI, kubernetesCLIArgs("describe", "node", nodeName)...)
+		})
+	}
+}
+
+// tridentWorkloadSelector selects the Trident workloads by their labels rather than their names,
+// which differ between installs.
+func tridentWorkloadSelector(labelValues ...string) string {
+	return fmt.Sprintf("%s in (%s)", TridentCSILabelKey, strings.Join(labelValues, ","))
+}
+
+// getTridentDeployments returns the specs of the Trident controller deployment, whether CSI or
+// not, and of the Trident operator if there is one, as YAML.  A comment notes whether Trident
+// was installed by the operator or with tridentctl.
+func getTridentDeployments() ([]byte, error) {
+
+	var deployments appsv1.DeploymentList
+	selector := tridentWorkloadSelector(TridentCSILabelValue, TridentLegacyLabelValue, TridentOperatorLabelValue)
+	if err := getKubernetesObjects(&deployments, "get", "deployment", "-n", TridentPodNamespace, "-l", selector,
+		"-o=json"); err != nil {
+		return nil, err
+	}
+
+	installer := "tridentctl"
+	for _, deployment := range deployments.Items {
+		if deployment.Labels[TridentOperatorLabelKey] == TridentOperatorLabelValue {
+			installer = "the Trident operator"
+		}
+	}
+
+	deploymentsYAML, err := yaml.Marshal(deployments)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(fmt.Sprintf("# Trident was installed by %s\n", installer)), deploymentsYAML...), nil
+}
+
+// getTridentDaemonSets returns the spec of the Trident node daemonset as YAML.
+func getTridentDaemonSets() ([]byte, error) {
+
+	var daemonSets appsv1.DaemonSetList
+	if err := getKubernetesObjects(&daemonSets, "get", "daemonset", "-n", TridentPodNamespace, "-l",
+		TridentNodeLabel, "-o=json"); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(daemonSets)
+}
+
+// getClusterResources returns every cluster-scoped resource of a type as YAML.  A type that is
+// not installed, such as the snapshot classes of a cluster without the snapshot CRDs, is noted
+// instead.
+func getClusterResources(resource string) ([]byte, error) {
+
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs("get", resource, "-o=yaml")...)
+	if err != nil {
+		if strings.Contains(err.Error(), "the server doesn't have a resource type") {
+			return []byte(fmt.Sprintf("# Resource type %s is not installed\n", resource)), nil
+		}
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// listTridentPVs returns the PVs provisioned by the Trident CSI driver.
+func listTridentPVs() (*k8s.PersistentVolumeList, error) {
+
+	var pvs k8s.PersistentVolumeList
+	if err := getKubernetesObjects(&pvs, "get", "pv", "-o=json"); err != nil {
+		return nil, err
+	}
+
+	tridentPVs := pvs.Items[:0]
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == frontendcsi.Provisioner {
+			tridentPVs = append(tridentPVs, pv)
+		}
+	}
+	pvs.Items = tridentPVs
+
+	return &pvs, nil
+}
+
+// getTridentPVs returns the PVs provisioned by the Trident CSI driver, as YAML.
+func getTridentPVs() ([]byte, error) {
+
+	pvs, err := listTridentPVs()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(pvs)
+}
+
+// getTridentPVCs returns the PVCs in every namespace that are bound to a Trident PV or waiting
+// for Trident to provision one, as YAML.
+func getTridentPVCs() ([]byte, error) {
+
+	pvs, err := listTridentPVs()
+	if err != nil {
+		return nil, err
+	}
+	tridentPVNames := make(map[string]bool, len(pvs.Items))
+	for _, pv := range pvs.Items {
+		tridentPVNames[pv.Name] = true
+	}
+
+	var pvcs k8s.PersistentVolumeClaimList
+	if err = getKubernetesObjects(&pvcs, "get", "pvc", "--all-namespaces", "-o=json"); err != nil {
+		return nil, err
+	}
+
+	tridentPVCs := pvcs.Items[:0]
+	for _, pvc := range pvcs.Items {
+		if tridentPVNames[pvc.Spec.VolumeName] ||
+			pvc.Annotations[storageProvisionerAnnotation] == frontendcsi.Provisioner {
+			tridentPVCs = append(tridentPVCs, pvc)
+		}
+	}
+	pvcs.Items = tridentPVCs
+
+	return yaml.Marshal(pvcs)
+}
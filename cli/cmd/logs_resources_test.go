@@ -0,0 +1,124 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+	k8s "k8s.io/api/core/v1"
+)
+
+const resourcesPVListJSON = `{"kind": "List", "apiVersion": "v1", "items": [
+	{"metadata": {"name": "pvc-1"}, "spec": {"csi": {"driver": "csi.trident.netapp.io", "volumeHandle": "pvc-1"}}},
+	{"metadata": {"name": "pvc-2"}, "spec": {"csi": {"driver": "ebs.csi.aws.com", "volumeHandle": "vol-2"}}},
+	{"metadata": {"name": "pv-nfs"}, "spec": {"nfs": {"server": "10.0.0.1", "path": "/export"}}}
+]}`
+
+func TestGetTridentPVs(t *testing.T) {
+
+	runner := &fakeCommandRunner{outputs: map[string]string{"get pv -o=json": resourcesPVListJSON}}
+	defer useFakeCommandRunner(runner)()
+
+	pvYAML, err := getTridentPVs()
+	assert.Nil(t, err)
+
+	var pvs k8s.PersistentVolumeList
+	assert.Nil(t, yaml.Unmarshal(pvYAML, &pvs))
+	assert.Len(t, pvs.Items, 1)
+	assert.Equal(t, "pvc-1", pvs.Items[0].Name)
+}
+
+func TestGetTridentPVCs(t *testing.T) {
+
+	runner := &fakeCommandRunner{outputs: map[string]string{
+		"get pv -o=json": resourcesPVListJSON,
+		"get pvc --all-namespaces -o=json": `{"items": [
+			{"metadata": {"name": "bound", "namespace": "apps"}, "spec": {"volumeName": "pvc-1"}},
+			{"metadata": {"name": "pending", "namespace": "apps",
+				"annotations": {"volume.beta.kubernetes.io/storage-provisioner": "csi.trident.netapp.io"}}},
+			{"metadata": {"name": "other", "namespace": "apps"}, "spec": {"volumeName": "pvc-2"}}
+		]}`,
+	}}
+	defer useFakeCommandRunner(runner)()
+
+	pvcYAML, err := getTridentPVCs()
+	assert.Nil(t, err)
+
+	var pvcs k8s.PersistentVolumeClaimList
+	assert.Nil(t, yaml.Unmarshal(pvcYAML, &pvcs))
+	var names []string
+	for _, pvc := range pvcs.Items {
+		names = append(names, pvc.Name)
+	}
+	assert.Equal(t, []string{"bound", "pending"}, names)
+}
+
+func TestGetClusterResources(t *testing.T) {
+
+	runner := &fakeCommandRunner{
+		outputs: map[string]string{"get storageclass -o=yaml": "items:\n- metadata:\n    name: gold\n"},
+		failures: map[string]string{
+			"get volumesnapshotclass -o=yaml": `error: the server doesn't have a resource type "volumesnapshotclass"`,
+			"get volumesnapshot -o=yaml":      "forbidden",
+		},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	output, err := getClusterResources("storageclass")
+	assert.Nil(t, err)
+	assert.Equal(t, "items:\n- metadata:\n    name: gold\n", string(output))
+
+	output, err = getClusterResources("volumesnapshotclass")
+	assert.Nil(t, err)
+	assert.Equal(t, "# Resource type volumesnapshotclass is not installed\n", string(output))
+
+	_, err = getClusterResources("volumesnapshot")
+	assert.EqualError(t, err, "forbidden")
+}
+
+func TestGetTridentDeployments(t *testing.T) {
+
+	selector := "app in (controller.csi.trident.netapp.io,trident.netapp.io,operator.trident.netapp.io)"
+	command := "get deployment -n trident -l " + selector + " -o=json"
+
+	runner := &fakeCommandRunner{outputs: map[string]string{command: `{"items": [
+		{"metadata": {"name": "trident-csi", "labels": {"app": "controller.csi.trident.netapp.io"}}}
+	]}`}}
+	defer useFakeCommandRunner(runner)()
+
+	deploymentsYAML, err := getTridentDeployments()
+	assert.Nil(t, err)
+	assert.Contains(t, string(deploymentsYAML), "# Trident was installed by tridentctl\n")
+	assert.Contains(t, string(deploymentsYAML), "name: trident-csi")
+
+	runner.outputs[command] = `{"items": [
+		{"metadata": {"name": "trident-csi", "labels": {"app": "controller.csi.trident.netapp.io"}}},
+		{"metadata": {"name": "trident-operator", "labels": {"app": "operator.trident.netapp.io"}}}
+	]}`
+	deploymentsYAML, err = getTridentDeployments()
+	assert.Nil(t, err)
+	assert.Contains(t, string(deploymentsYAML), "# Trident was installed by the Trident operator\n")
+}
+
+func TestWriteNodeDescriptions(t *testing.T) {
+
+	runner := &fakeCommandRunner{
+		outputs: map[string]string{
+			listNodePodsCommand:      nodePodListJSON(t, map[string]string{"worker-2": "trident-csi-b", "worker-1": "trident-csi-a"}),
+			"describe node worker-1": "Name: worker-1\nConditions:\n  DiskPressure   False\n",
+		},
+		failures: map[string]string{"describe node worker-2": "forbidden"},
+	}
+	defer useFakeCommandRunner(runner)()
+
+	var console bytes.Buffer
+	consoleOutput = &console
+
+	writeNodeDescriptions()
+	assert.Equal(t, "node-describe-worker-1 log:\nName: worker-1\nConditions:\n  DiskPressure   False\n\n",
+		console.String())
+	assert.Equal(t, "could not collect node-describe-worker-2; forbidden", logErrors.String())
+}
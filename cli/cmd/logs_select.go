@@ -0,0 +1,64 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+var (
+	includedEntries []string
+	excludedEntries []string
+)
+
+func init() {
+	logsCmd.Flags().StringArrayVar(&includedEntries, "include", []string{}, "In archive mode, write only the logs whose names match this glob, e.g. 'trident-node-*'. May be repeated.")
+	logsCmd.Flags().StringArrayVar(&excludedEntries, "exclude", []string{}, "In archive mode, skip the logs whose names match this glob, e.g. '*sidecar*'. May be repeated.")
+}
+
+// checkValidEntryFilters checks that the entry filters are valid globs used in archive mode.
+func checkValidEntryFilters() error {
+
+	if len(includedEntries) == 0 && len(excludedEntries) == 0 {
+		return nil
+	}
+	if !archive {
+		return errors.New("--include and --exclude are only supported in archive mode")
+	}
+	for _, pattern := range append(append([]string{}, includedEntries...), excludedEntries...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s is not a valid glob; %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// archiveEntrySelected reports whether a log is written to the archive, which it is if it
+// matches any --include glob, or there are none, and matches no --exclude glob.
+func archiveEntrySelected(logName string) bool {
+
+	if !archive {
+		return true
+	}
+
+	selected := len(includedEntries) == 0
+	for _, pattern := range includedEntries {
+		if matched, _ := path.Match(pattern, logName); matched {
+			selected = true
+			break
+		}
+	}
+	for _, pattern := range excludedEntries {
+		if matched, _ := path.Match(pattern, logName); matched {
+			selected = false
+			break
+		}
+	}
+
+	if !selected && Debug {
+		fmt.Printf("Skipping log %s, which is filtered out with --include or --exclude\n", logName)
+	}
+	return selected
+}
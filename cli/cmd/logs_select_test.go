@@ -0,0 +1,46 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveEntrySelected(t *testing.T) {
+
+	savedArchive, savedIncluded, savedExcluded := archive, includedEntries, excludedEntries
+	defer func() { archive, includedEntries, excludedEntries = savedArchive, savedIncluded, savedExcluded }()
+
+	archive, includedEntries, excludedEntries = true, nil, nil
+	assert.True(t, archiveEntrySelected("trident"))
+
+	includedEntries = []string{"trident-node-*"}
+	assert.True(t, archiveEntrySelected("trident-node-worker-1"))
+	assert.True(t, archiveEntrySelected("trident-node-worker-1-sidecar-driver-registrar"))
+	assert.False(t, archiveEntrySelected("trident"))
+
+	excludedEntries = []string{"*sidecar*"}
+	assert.True(t, archiveEntrySelected("trident-node-worker-1"))
+	assert.False(t, archiveEntrySelected("trident-node-worker-1-sidecar-driver-registrar"))
+
+	// The filters only apply to archives
+	archive = false
+	assert.True(t, archiveEntrySelected("trident"))
+}
+
+func TestCheckValidEntryFilters(t *testing.T) {
+
+	savedArchive, savedIncluded, savedExcluded := archive, includedEntries, excludedEntries
+	defer func() { archive, includedEntries, excludedEntries = savedArchive, savedIncluded, savedExcluded }()
+
+	archive, includedEntries, excludedEntries = false, nil, []string{"*sidecar*"}
+	assert.EqualError(t, checkValidEntryFilters(), "--include and --exclude are only supported in archive mode")
+
+	archive = true
+	assert.Nil(t, checkValidEntryFilters())
+
+	includedEntries = []string{"trident-node-["}
+	assert.NotNil(t, checkValidEntryFilters())
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/netapp/trident/config"
+)
+
+var selectedSidecars []string
+
+func init() {
+	logsCmd.Flags().StringArrayVar(&selectedSidecars, "sidecar", nil, "Get the logs of only this sidecar container, e.g. csi-provisioner, rather than of all sidecars. May be repeated.")
+}
+
+// checkValidSidecars checks the sidecars selected with --sidecar, which imply --sidecars.
+func checkValidSidecars() error {
+
+	if len(selectedSidecars) == 0 {
+		return nil
+	}
+	if sidecarsSet && !sidecars {
+		return errors.New("--sidecar cannot be used with --sidecars=false")
+	}
+	for _, sidecar := range selectedSidecars {
+		if sidecar == config.ContainerTrident {
+			return fmt.Errorf("%s is the main Trident container, not a sidecar", sidecar)
+		}
+	}
+	sidecars = true
+
+	return nil
+}
+
+// checkSelectedSidecars checks the sidecars selected with --sidecar against the containers of the
+// Trident controller pod and of a node pod before any log is collected, listing the sidecars
+// there are if any selected sidecar is not among them.
+func checkSelectedSidecars() error {
+
+	if len(selectedSidecars) == 0 {
+		return nil
+	}
+
+	var pods []string
+	if TridentPodName != "" {
+		pods = append(pods, TridentPodName)
+	}
+	// Every node pod is created by the same daemonset, so one has the sidecars of all.  Any failure
+	// to find them is reported when collecting the node logs.
+	if tridentNodes, err := listTridentNodes(TridentPodNamespace, ""); err == nil {
+		nodeNames := make([]string, 0, len(tridentNodes))
+		for nodeName := range tridentNodes {
+			nodeNames = append(nodeNames, nodeName)
+		}
+		if len(nodeNames) > 0 {
+			sort.Strings(nodeNames)
+			pods = append(pods, tridentNodes[nodeNames[0]])
+		}
+	}
+	if len(pods) == 0 {
+		return nil
+	}
+
+	available := make(map[string]struct{})
+	for _, pod := range pods {
+		podSidecars, err := listTridentSidecars(pod, TridentPodNamespace)
+		if err != nil {
+			return fmt.Errorf("could not list the sidecars of pod %s to check --sidecar; %v", pod, err)
+		}
+		for _, sidecar := range podSidecars {
+			available[sidecar] = struct{}{}
+		}
+	}
+
+	var missing []string
+	for _, sidecar := range selectedSidecars {
+		if _, ok := available[sidecar]; !ok {
+			missing = append(missing, sidecar)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if len(available) == 0 {
+		return fmt.Errorf("no sidecar named %s was found in the Trident pods, which have no sidecars",
+			strings.Join(missing, ", "))
+	}
+	sidecarNames := make([]string, 0, len(available))
+	for sidecar := range available {
+		sidecarNames = append(sidecarNames, sidecar)
+	}
+	sort.Strings(sidecarNames)
+	return fmt.Errorf("no sidecar named %s was found in the Trident pods; the sidecars are %s",
+		strings.Join(missing, ", "), strings.Join(sidecarNames, ", "))
+}
+
+// selectSidecars returns the sidecars of a pod whose logs are collected, which are those selected
+// with --sidecar, or all of them if none were.  The controller and node pods have different
+// sidecars, so a pod may have none of those selected.
+func selectSidecars(podSidecars []string) []string {
+
+	if len(selectedSidecars) == 0 {
+		return podSidecars
+	}
+
+	var selected []string
+	for _, sidecar := range podSidecars {
+		for _, selectedSidecar := range selectedSidecars {
+			if sidecar == selectedSidecar {
+				selected = append(selected, sidecar)
+			}
+		}
+	}
+	return selected
+}
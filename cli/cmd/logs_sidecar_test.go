@@ -0,0 +1,83 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8s "k8s.io/api/core/v1"
+)
+
+func TestSelectSidecars(t *testing.T) {
+
+	defer func() { selectedSidecars = nil }()
+
+	controllerSidecars := []string{"csi-provisioner", "csi-attacher", "csi-resizer"}
+
+	selectedSidecars = nil
+	assert.Equal(t, controllerSidecars, selectSidecars(controllerSidecars))
+
+	selectedSidecars = []string{"csi-attacher", "csi-provisioner"}
+	assert.Equal(t, []string{"csi-provisioner", "csi-attacher"}, selectSidecars(controllerSidecars))
+	assert.Empty(t, selectSidecars([]string{"driver-registrar"}))
+}
+
+func sidecarPodJSON(t *testing.T, containers ...string) string {
+
+	var pod k8s.Pod
+	for _, container := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, k8s.Container{Name: container})
+	}
+	podJSON, err := json.Marshal(pod)
+	assert.Nil(t, err)
+	return string(podJSON)
+}
+
+func TestCheckSelectedSidecars(t *testing.T) {
+
+	runner := &fakeCommandRunner{outputs: map[string]string{
+		"get pod trident-csi-0 -n trident -o=json": sidecarPodJSON(t, "trident-main", "csi-provisioner",
+			"csi-attacher"),
+		listNodePodsCommand: nodePodListJSON(t, map[string]string{
+			"worker-2": "trident-csi-b", "worker-1": "trident-csi-a",
+		}),
+		"get pod trident-csi-a -n trident -o=json": sidecarPodJSON(t, "trident-main", "driver-registrar"),
+	}}
+	defer useFakeCommandRunner(runner)()
+	savedPodName := TridentPodName
+	defer func() { selectedSidecars, TridentPodName = nil, savedPodName }()
+	TridentPodName = "trident-csi-0"
+
+	selectedSidecars = nil
+	assert.Nil(t, checkSelectedSidecars())
+	assert.Empty(t, runner.commands, "nothing should be checked without --sidecar")
+
+	selectedSidecars = []string{"csi-attacher", "driver-registrar"}
+	assert.Nil(t, checkSelectedSidecars())
+
+	selectedSidecars = []string{"csi-snapshoter", "csi-attacher", "external-resizer"}
+	assert.EqualError(t, checkSelectedSidecars(), "no sidecar named csi-snapshoter, external-resizer was "+
+		"found in the Trident pods; the sidecars are csi-attacher, csi-provisioner, driver-registrar")
+
+	runner.outputs["get pod trident-csi-0 -n trident -o=json"] = sidecarPodJSON(t, "trident-main")
+	runner.outputs["get pod trident-csi-a -n trident -o=json"] = sidecarPodJSON(t, "trident-main")
+	assert.EqualError(t, checkSelectedSidecars(), "no sidecar named csi-snapshoter, csi-attacher, "+
+		"external-resizer was found in the Trident pods, which have no sidecars")
+}
+
+func TestCheckValidSidecars(t *testing.T) {
+
+	defer func() { selectedSidecars, sidecars, sidecarsSet = nil, false, false }()
+
+	selectedSidecars, sidecars, sidecarsSet = []string{"csi-provisioner"}, false, false
+	assert.Nil(t, checkValidSidecars())
+	assert.True(t, sidecars)
+
+	sidecars, sidecarsSet = false, true
+	assert.EqualError(t, checkValidSidecars(), "--sidecar cannot be used with --sidecars=false")
+
+	selectedSidecars, sidecarsSet = []string{"trident-main"}, false
+	assert.EqualError(t, checkValidSidecars(), "trident-main is the main Trident container, not a sidecar")
+}
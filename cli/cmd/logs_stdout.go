@@ -0,0 +1,65 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+)
+
+// The name reported for a support archive written to stdout
+const stdoutArchiveName = "stdout"
+
+var (
+	archiveToStdout bool
+	// The real stdout, while the informational output is redirected to stderr
+	archiveStdout io.Writer = os.Stdout
+)
+
+func init() {
+	logsCmd.Flags().BoolVar(&archiveToStdout, "stdout", false, "In archive mode, write the support archive to stdout, e.g. to pipe it to another tool, and everything else to stderr.")
+}
+
+// checkValidArchiveStdout checks that a support archive written to stdout is a single stream
+// with no file options.
+func checkValidArchiveStdout() error {
+
+	if !archiveToStdout {
+		return nil
+	}
+	if !archive || estimate {
+		return errors.New("--stdout is only supported in archive mode")
+	}
+	if archiveName != "" || outputDir != "" || forceArchive {
+		return errors.New("--stdout cannot be used with --filename, --output-dir or --force")
+	}
+	if splitSize != "" || uploadS3 != "" || uploadURL != "" {
+		return errors.New("--stdout cannot be used with --split-size or the upload options")
+	}
+	if OutputFormat == FormatJSON {
+		return errors.New("--stdout cannot be used with JSON output")
+	}
+	return nil
+}
+
+// redirectStdoutToStderr sends everything printed to stdout to stderr instead, so that only the
+// support archive is written to stdout, and returns a function undoing it.
+func redirectStdoutToStderr() func() {
+
+	savedStdout := os.Stdout
+	archiveStdout, os.Stdout = savedStdout, os.Stderr
+
+	return func() {
+		os.Stdout, archiveStdout = savedStdout, savedStdout
+	}
+}
+
+// createArchiveStdout returns a buffered writer of the support archive to stdout, encrypting it
+// if a password was specified, and a function flushing it, which fails if stdout was closed early,
+// such as by the end of a pipe.
+func createArchiveStdout() (io.Writer, func() error, error) {
+	bufferedStdout := bufio.NewWriter(archiveStdout)
+	return encryptArchiveOutput(bufferedStdout, bufferedStdout.Flush)
+}
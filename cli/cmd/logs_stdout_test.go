@@ -0,0 +1,80 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateArchiveStdout(t *testing.T) {
+
+	var stdout bytes.Buffer
+	savedStdout, savedPassword := archiveStdout, archivePassword
+	defer func() { archiveStdout, archivePassword = savedStdout, savedPassword }()
+	archiveStdout, archivePassword = &stdout, ""
+
+	output, closeOutput, err := createArchiveStdout()
+	assert.Nil(t, err)
+	writer, err := newArchiveWriter(archiveFormatZip, output)
+	assert.Nil(t, err)
+	_, err = writer.WriteEntry("trident", []byte("level=info msg=\"Started.\"\n"), true)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, closeOutput())
+
+	reader, err := zip.NewReader(bytes.NewReader(stdout.Bytes()), int64(stdout.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, reader.File, 1)
+	entry, err := reader.File[0].Open()
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, "level=info msg=\"Started.\"\n", string(content))
+}
+
+// brokenPipe fails every write, as stdout does once the reader of its pipe has exited.
+type brokenPipe struct{}
+
+func (brokenPipe) Write([]byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+func TestCreateArchiveStdoutBrokenPipe(t *testing.T) {
+
+	savedStdout, savedPassword := archiveStdout, archivePassword
+	defer func() { archiveStdout, archivePassword = savedStdout, savedPassword }()
+
+	for _, password := range []string{"", "secret"} {
+		archiveStdout, archivePassword = brokenPipe{}, password
+
+		output, closeOutput, err := createArchiveStdout()
+		assert.Nil(t, err)
+		writer, err := newArchiveWriter(archiveFormatZip, output)
+		assert.Nil(t, err)
+		_, err = writer.WriteEntry("trident", []byte("level=info msg=\"Started.\"\n"), true)
+		assert.Nil(t, err, "the entry should only be buffered")
+
+		assert.EqualError(t, closeSupportArchive(writer, closeOutput), syscall.EPIPE.Error(), password)
+	}
+}
+
+func TestCheckValidArchiveStdout(t *testing.T) {
+
+	savedStdout, savedArchive, savedName := archiveToStdout, archive, archiveName
+	defer func() { archiveToStdout, archive, archiveName = savedStdout, savedArchive, savedName }()
+
+	archiveToStdout, archive, archiveName = true, false, ""
+	assert.EqualError(t, checkValidArchiveStdout(), "--stdout is only supported in archive mode")
+
+	archive = true
+	assert.Nil(t, checkValidArchiveStdout())
+
+	archiveName = "support.zip"
+	assert.NotNil(t, checkValidArchiveStdout())
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const awsCLI = "aws"
+
+var (
+	uploadS3    string
+	uploadURL   string
+	noKeepLocal bool
+)
+
+func init() {
+	logsCmd.Flags().StringVar(&uploadS3, "upload-s3", "", "Upload the support archive to this S3 location, e.g. s3://bucket/prefix, with the AWS CLI and its usual credentials.")
+	logsCmd.Flags().StringVar(&uploadURL, "upload-url", "", "Upload the support archive with an HTTP PUT to this URL, such as a presigned upload link provided by support.")
+	logsCmd.Flags().BoolVar(&noKeepLocal, "no-keep-local", false, "Delete the support archive once it has been uploaded.")
+}
+
+// checkValidUpload checks the upload options.
+func checkValidUpload() error {
+
+	if uploadS3 == "" && uploadURL == "" {
+		if noKeepLocal {
+			return errors.New("--no-keep-local requires an upload option")
+		}
+		return nil
+	}
+
+	if !archive || estimate {
+		return errors.New("uploading is only supported in archive mode")
+	}
+	if uploadS3 != "" {
+		if _, _, err := parseS3Target(uploadS3); err != nil {
+			return err
+		}
+	}
+	if uploadURL != "" {
+		// A presigned URL is for one object, so it cannot take several volumes
+		if splitSize != "" {
+			return errors.New("--upload-url cannot be used with --split-size")
+		}
+		if parsedURL, err := url.Parse(uploadURL); err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			return fmt.Errorf("%s is not a valid HTTP(S) upload URL", redactedURL(uploadURL))
+		}
+	}
+
+	return nil
+}
+
+// parseS3Target returns the bucket and key prefix of an s3://bucket/prefix location.
+func parseS3Target(target string) (string, string, error) {
+
+	location := strings.TrimPrefix(target, "s3://")
+	if location == target {
+		return "", "", fmt.Errorf("%s is not a valid S3 location; use s3://bucket/prefix", target)
+	}
+
+	parts := strings.SplitN(location, "/", 2)
+	if parts[0] == "" {
+		return "", "", fmt.Errorf("%s is not a valid S3 location; the bucket is missing", target)
+	}
+	if len(parts) == 1 {
+		return parts[0], "", nil
+	}
+	return parts[0], strings.Trim(parts[1], "/"), nil
+}
+
+// uploadArchive uploads the files of the support archive, deleting them afterward if requested.
+func uploadArchive() error {
+
+	for _, fileName := range archiveFileNames() {
+		if uploadS3 != "" {
+			objectURL, err := uploadFileToS3(fileName, uploadS3)
+			if err != nil {
+				return fmt.Errorf("could not upload %s to %s; %v", fileName, uploadS3, err)
+			}
+			printArchiveResult("Uploaded %s to %s.\n", fileName, objectURL)
+		}
+
+		if uploadURL != "" {
+			status, err := uploadFileToURL(fileName, uploadURL)
+			if err != nil {
+				return fmt.Errorf("could not upload %s to %s; %v", fileName, redactedURL(uploadURL), err)
+			}
+			printArchiveResult("Uploaded %s to %s; %s.\n", fileName, redactedURL(uploadURL), status)
+		}
+
+		if noKeepLocal {
+			if err := os.Remove(fileName); err != nil {
+				return fmt.Errorf("could not delete %s after uploading it; %v", fileName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// uploadFileToS3 copies a file to an S3 location with the AWS CLI, which finds the credentials
+// in the environment, the AWS configuration files, or the instance role, and returns the URL of
+// the uploaded object.
+func uploadFileToS3(fileName, target string) (string, error) {
+
+	bucket, prefix, err := parseS3Target(target)
+	if err != nil {
+		return "", err
+	}
+	key := path.Join(prefix, filepath.Base(fileName))
+
+	uploadCommand := []string{"s3", "cp", fileName, "s3://" + bucket + "/" + key, "--only-show-errors"}
+	printInvokedCommand(awsCLI, uploadCommand)
+
+	// An upload may take much longer than any Kubernetes request, so it has no timeout
+	var stderr bytes.Buffer
+	cmd := exec.Command(awsCLI, uploadCommand...)
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(message)
+		}
+		return "", err
+	}
+
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", bucket, key), nil
+}
+
+// uploadFileToURL copies a file to a URL with an HTTP PUT and returns the response status.  Any
+// status other than success is an error.
+func uploadFileToURL(fileName, uploadURL string) (string, error) {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequest(http.MethodPut, uploadURL, file)
+	if err != nil {
+		return "", err
+	}
+	request.ContentLength = fileInfo.Size()
+
+	if Debug {
+		fmt.Printf("Uploading %s to %s\n", fileName, redactedURL(uploadURL))
+	}
+
+	// An upload may take much longer than any REST request, so it has no timeout
+	response, err := (&http.Client{}).Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		return "", fmt.Errorf("the server returned %s; %s", response.Status, strings.TrimSpace(string(responseBody)))
+	}
+
+	return response.Status, nil
+}
+
+// redactedURL returns a URL without its query, which in a presigned URL holds the credentials.
+func redactedURL(rawURL string) string {
+	if queryIndex := strings.IndexByte(rawURL, '?'); queryIndex >= 0 {
+		return rawURL[:queryIndex]
+	}
+	return rawURL
+}
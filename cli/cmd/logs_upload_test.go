@@ -0,0 +1,74 @@
+// Copyright 2020 NetApp, Inc. All Rights Reserved.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseS3Target(t *testing.T) {
+
+	tests := []struct {
+		target string
+		bucket string
+		prefix string
+	}{
+		{"s3://support", "support", ""},
+		{"s3://support/", "support", ""},
+		{"s3://support/cases/1234/", "support", "cases/1234"},
+	}
+	for _, test := range tests {
+		bucket, prefix, err := parseS3Target(test.target)
+		assert.Nil(t, err, test.target)
+		assert.Equal(t, test.bucket, bucket, test.target)
+		assert.Equal(t, test.prefix, prefix, test.target)
+	}
+
+	for _, target := range []string{"support/cases", "s3://", "s3:///cases", "https://support/cases"} {
+		_, _, err := parseS3Target(target)
+		assert.NotNil(t, err, target)
+	}
+}
+
+func TestUploadFileToURL(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "upload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "support.zip")
+	assert.Nil(t, ioutil.WriteFile(fileName, []byte("archive"), 0644))
+
+	var uploaded []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("signature") != "valid" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("signature does not match\n"))
+			return
+		}
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, int64(7), r.ContentLength)
+		uploaded, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	status, err := uploadFileToURL(fileName, server.URL+"/upload?signature=valid")
+	assert.Nil(t, err)
+	assert.Equal(t, "200 OK", status)
+	assert.Equal(t, "archive", string(uploaded))
+
+	_, err = uploadFileToURL(fileName, server.URL+"/upload?signature=expired")
+	assert.EqualError(t, err, "the server returned 403 Forbidden; signature does not match")
+}
+
+func TestRedactedURL(t *testing.T) {
+	assert.Equal(t, "https://uploads.example.com/case/1234",
+		redactedURL("https://uploads.example.com/case/1234?X-Amz-Signature=abc"))
+	assert.Equal(t, "https://uploads.example.com/case/1234", redactedURL("https://uploads.example.com/case/1234"))
+}
@@ -4,9 +4,13 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
@@ -15,7 +19,10 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
 	k8s "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/netapp/trident/cli/api"
 	"github.com/netapp/trident/config"
@@ -36,8 +43,10 @@ const (
 
 	PodServer = "127.0.0.1:8000"
 
-	ExitCodeSuccess = 0
-	ExitCodeFailure = 1
+	ExitCodeSuccess        = 0
+	ExitCodeFailure        = 1
+	ExitCodePartialFailure = 2
+	ExitCodeTotalFailure   = 3
 
 	TridentLegacyLabelKey   = "app"
 	TridentLegacyLabelValue = "trident.netapp.io"
@@ -58,6 +67,10 @@ const (
 	TridentMigratorLabelKey   = "app"
 	TridentMigratorLabelValue = "trident-migrator.netapp.io"
 	TridentMigratorLabel      = TridentMigratorLabelKey + "=" + TridentMigratorLabelValue
+
+	TridentOperatorLabelKey   = "app"
+	TridentOperatorLabelValue = "operator.trident.netapp.io"
+	TridentOperatorLabel      = TridentOperatorLabelKey + "=" + TridentOperatorLabelValue
 )
 
 var (
@@ -67,6 +80,17 @@ var (
 	TridentPodNamespace string
 	ExitCode            int
 
+	// The kubeconfig file and context used by each Kubernetes CLI command, if not the defaults
+	KubernetesConfig  string
+	KubernetesContext string
+	// Whether tridentctl is running in a pod with a service account and no kubeconfig
+	InCluster bool
+	// The service account files mounted in every pod, where the token shows tridentctl is in-cluster
+	inClusterTokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	// The Kubernetes CLI to use, if not discovered
+	KubernetesCLIOverride string
+
 	Debug        bool
 	Server       string
 	OutputFormat string
@@ -125,9 +149,17 @@ func discoverOperatingMode(_ *cobra.Command) error {
 		return err
 	}
 
+	// In a pod, the CLI falls back to the service account, which also names the pod's namespace
+	InCluster = detectInCluster()
+
 	// Server not specified, so try tunneling to a pod
 	if TridentPodNamespace == "" {
-		if TridentPodNamespace, err = getCurrentNamespace(); err != nil {
+		if InCluster {
+			TridentPodNamespace, err = getInClusterNamespace()
+		} else {
+			TridentPodNamespace, err = getCurrentNamespace()
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -147,20 +179,39 @@ func discoverOperatingMode(_ *cobra.Command) error {
 
 func discoverKubernetesCLI() error {
 
+	// Use the CLI chosen explicitly, if any
+	if KubernetesCLIOverride != "" {
+		if KubernetesCLIOverride != CLIKubernetes && KubernetesCLIOverride != CLIOpenshift {
+			return fmt.Errorf("%s is not a valid Kubernetes CLI; use %s or %s",
+				KubernetesCLIOverride, CLIKubernetes, CLIOpenshift)
+		}
+		_, err := exec.Command(KubernetesCLIOverride, kubernetesCLIArgs("version")...).Output()
+		if GetExitCodeFromError(err) == ExitCodeSuccess {
+			KubernetesCLI = KubernetesCLIOverride
+			return nil
+		}
+		return kubernetesCLIError(err)
+	}
+
 	// Try the OpenShift CLI first
-	_, err := exec.Command(CLIOpenshift, "version").Output()
+	_, err := exec.Command(CLIOpenshift, kubernetesCLIArgs("version")...).Output()
 	if GetExitCodeFromError(err) == ExitCodeSuccess {
 		KubernetesCLI = CLIOpenshift
 		return nil
 	}
 
 	// Fall back to the K8S CLI
-	_, err = exec.Command(CLIKubernetes, "version").Output()
+	_, err = exec.Command(CLIKubernetes, kubernetesCLIArgs("version")...).Output()
 	if GetExitCodeFromError(err) == ExitCodeSuccess {
 		KubernetesCLI = CLIKubernetes
 		return nil
 	}
 
+	return kubernetesCLIError(err)
+}
+
+func kubernetesCLIError(err error) error {
+
 	if ee, ok := err.(*exec.ExitError); ok {
 		return fmt.Errorf("found the Kubernetes CLI, but it exited with error: %s",
 			strings.TrimRight(string(ee.Stderr), "\n"))
@@ -169,11 +220,117 @@ func discoverKubernetesCLI() error {
 	return fmt.Errorf("could not find the Kubernetes CLI: %v", err)
 }
 
+// commandRunner runs the external commands of the CLI, so that tests may replace them.
+type commandRunner interface {
+	// Run runs a command and returns its standard output.  If the command fails, the error is
+	// described by its standard error, if any.
+	Run(name string, args ...string) ([]byte, error)
+}
+
+// commandStreamer is implemented by command runners that can copy the standard output of a
+// command as it is produced, rather than returning all of it at once.
+type commandStreamer interface {
+	Stream(stdout io.Writer, name string, args ...string) error
+}
+
+var cliRunner commandRunner = execCommandRunner{}
+
+// execCommandRunner runs commands as child processes, killing any that run longer than the
+// --request-timeout duration.
+type execCommandRunner struct{}
+
+func (r execCommandRunner) Run(name string, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	err := r.Stream(&stdout, name, args...)
+	return stdout.Bytes(), err
+}
+
+func (execCommandRunner) Stream(stdout io.Writer, name string, args ...string) error {
+
+	ctx, cancel := requestContext()
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil && collectionContext.Err() != nil {
+		return &commandFailure{err: err, message: "collection interrupted"}
+	} else if ctx.Err() == context.DeadlineExceeded {
+		return &commandFailure{err: err, message: fmt.Sprintf("timed out after %v running %s %s",
+			requestTimeout, name, strings.Join(args, " "))}
+	} else if err != nil {
+		return &commandFailure{err: err, message: strings.TrimSpace(stderr.String())}
+	}
+	return nil
+}
+
+// commandFailure is the failure of a command, described by its error output if it had any.
+type commandFailure struct {
+	err     error
+	message string
+}
+
+func (f *commandFailure) Error() string {
+	if f.message != "" {
+		return f.message
+	}
+	return f.err.Error()
+}
+
+// kubernetesCLIArgs adds any global options, such as the kubeconfig file and context, to the
+// arguments of a Kubernetes CLI command.  They come first, so they are not mistaken for arguments
+// of a command run in a container.
+func kubernetesCLIArgs(args ...string) []string {
+	globalArgs := kubernetesCLIGlobalArgs()
+	if len(globalArgs) == 0 {
+		return args
+	}
+	return append(globalArgs, args...)
+}
+
+func kubernetesCLIGlobalArgs() []string {
+	var globalArgs []string
+	if KubernetesConfig != "" {
+		globalArgs = append(globalArgs, "--kubeconfig="+KubernetesConfig)
+	}
+	if KubernetesContext != "" {
+		globalArgs = append(globalArgs, "--context="+KubernetesContext)
+	}
+	return globalArgs
+}
+
+// detectInCluster reports whether tridentctl is running in a pod with a service account token
+// and without any kubeconfig, in which case the Kubernetes CLI and API use the service account.
+func detectInCluster() bool {
+
+	if KubernetesConfig != "" || os.Getenv("KUBECONFIG") != "" || os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return false
+	}
+	if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
+		return false
+	}
+	_, err := os.Stat(inClusterTokenFile)
+	return err == nil
+}
+
+// getInClusterNamespace returns the namespace of the pod tridentctl is running in.
+func getInClusterNamespace() (string, error) {
+
+	namespace, err := ioutil.ReadFile(inClusterNamespaceFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read the service account namespace; %v", err)
+	}
+	return strings.TrimSpace(string(namespace)), nil
+}
+
 // getCurrentNamespace returns the default namespace from service account info
 func getCurrentNamespace() (string, error) {
 
 	// Get current namespace from service account info
-	cmd := exec.Command(KubernetesCLI, "get", "serviceaccount", "default", "-o=json")
+	cmd := exec.Command(KubernetesCLI, kubernetesCLIArgs("get", "serviceaccount", "default", "-o=json")...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return "", err
@@ -202,14 +359,13 @@ func getCurrentNamespace() (string, error) {
 func getTridentPod(namespace, appLabel string) (string, error) {
 
 	// Get 'trident' pod info
-	cmd := exec.Command(
-		KubernetesCLI,
+	cmd := exec.Command(KubernetesCLI, kubernetesCLIArgs(
 		"get", "pod",
 		"-n", namespace,
 		"-l", appLabel,
 		"-o=json",
 		"--field-selector=status.phase=Running",
-	)
+	)...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return "", err
@@ -241,26 +397,18 @@ func getTridentPod(namespace, appLabel string) (string, error) {
 func listTridentSidecars(podName, podNameSpace string) ([]string, error) {
 	// Get 'trident' pod info
 	var sidecarNames []string
-	cmd := exec.Command(
-		KubernetesCLI,
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(
 		"get", "pod",
 		podName,
 		"-n", podNameSpace,
 		"-o=json",
-	)
-	stdout, err := cmd.StdoutPipe()
+	)...)
 	if err != nil {
 		return sidecarNames, err
 	}
-	if err = cmd.Start(); err != nil {
-		return sidecarNames, err
-	}
 
 	var tridentPod k8s.Pod
-	if err = json.NewDecoder(stdout).Decode(&tridentPod); err != nil {
-		return sidecarNames, err
-	}
-	if err = cmd.Wait(); err != nil {
+	if err = json.Unmarshal(output, &tridentPod); err != nil {
 		return sidecarNames, err
 	}
 
@@ -274,29 +422,37 @@ func listTridentSidecars(podName, podNameSpace string) ([]string, error) {
 	return sidecarNames, nil
 }
 
+// listTridentInitContainers returns a list of init container names inside a trident pod
+func listTridentInitContainers(podName, podNameSpace string) ([]string, error) {
+
+	var initContainerNames []string
+	var tridentPod k8s.Pod
+	if err := getKubernetesObjects(&tridentPod, "get", "pod", podName, "-n", podNameSpace, "-o=json"); err != nil {
+		return initContainerNames, err
+	}
+
+	for _, initContainer := range tridentPod.Spec.InitContainers {
+		initContainerNames = append(initContainerNames, initContainer.Name)
+	}
+
+	return initContainerNames, nil
+}
+
 func getTridentNode(nodeName, namespace string) (string, error) {
 	selector := fmt.Sprintf("--field-selector=spec.nodeName=%s", nodeName)
-	cmd := exec.Command(
-		KubernetesCLI,
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(
 		"get", "pod",
 		"-n", namespace,
 		"-l", TridentNodeLabel,
 		"-o=json",
 		selector,
-	)
-	stdout, err := cmd.StdoutPipe()
+	)...)
 	if err != nil {
 		return "", err
 	}
-	if err = cmd.Start(); err != nil {
-		return "", err
-	}
 
 	var tridentPods k8s.PodList
-	if err = json.NewDecoder(stdout).Decode(&tridentPods); err != nil {
-		return "", err
-	}
-	if err = cmd.Wait(); err != nil {
+	if err = json.Unmarshal(output, &tridentPods); err != nil {
 		return "", err
 	}
 
@@ -311,31 +467,25 @@ func getTridentNode(nodeName, namespace string) (string, error) {
 	return name, nil
 }
 
-// listTridentNodes returns a list of names of the Trident node pods in the specified namespace
-func listTridentNodes(namespace string) (map[string]string, error) {
+// listTridentNodes returns a list of names of the Trident node pods in the specified namespace,
+// keyed by node name.  If a node label selector is specified, only the pods on matching nodes
+// are returned.
+func listTridentNodes(namespace, nodeSelector string) (map[string]string, error) {
 	// Get trident node pods info
 	tridentNodes := make(map[string]string)
-	cmd := exec.Command(
-		KubernetesCLI,
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(
 		"get", "pod",
 		"-n", namespace,
 		"-l", TridentNodeLabel,
 		"-o=json",
 		"--field-selector=status.phase=Running",
-	)
-	stdout, err := cmd.StdoutPipe()
+	)...)
 	if err != nil {
 		return tridentNodes, err
 	}
-	if err = cmd.Start(); err != nil {
-		return tridentNodes, err
-	}
 
 	var tridentPods k8s.PodList
-	if err = json.NewDecoder(stdout).Decode(&tridentPods); err != nil {
-		return tridentNodes, err
-	}
-	if err = cmd.Wait(); err != nil {
+	if err = json.Unmarshal(output, &tridentPods); err != nil {
 		return tridentNodes, err
 	}
 
@@ -349,9 +499,87 @@ func listTridentNodes(namespace string) (map[string]string, error) {
 		tridentNodes[pod.Spec.NodeName] = pod.Name
 	}
 
+	if nodeSelector != "" {
+		var nodes k8s.NodeList
+		if err = getKubernetesObjects(&nodes, "get", "node", "-l", nodeSelector, "-o=json"); err != nil {
+			return tridentNodes, fmt.Errorf("could not list nodes matching selector %s; %v", nodeSelector, err)
+		}
+		selectedNodes := make(map[string]string)
+		for _, node := range nodes.Items {
+			if pod, ok := tridentNodes[node.Name]; ok {
+				selectedNodes[node.Name] = pod
+			}
+		}
+		if len(selectedNodes) < 1 {
+			return selectedNodes, fmt.Errorf("could not find any Trident node pods in the %s namespace "+
+				"on nodes matching selector %s", namespace, nodeSelector)
+		}
+		tridentNodes = selectedNodes
+	}
+
 	return tridentNodes, nil
 }
 
+// getKubernetesObjects invokes the Kubernetes CLI with the specified arguments, which should
+// request JSON output, and decodes the result into the supplied object.
+func getKubernetesObjects(object interface{}, args ...string) error {
+
+	output, err := cliRunner.Run(KubernetesCLI, kubernetesCLIArgs(args...)...)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(output, object)
+}
+
+// listPodsOwnedBy returns the pods in the specified namespace that are owned by the specified
+// workload.  Pods owned by a deployment are found via its replica sets.
+func listPodsOwnedBy(kind, name, namespace string) ([]k8s.Pod, error) {
+
+	var pods k8s.PodList
+	if err := getKubernetesObjects(&pods, "get", "pod", "-n", namespace, "-o=json"); err != nil {
+		return nil, err
+	}
+
+	// Pods are owned by the deployment's replica sets rather than the deployment itself
+	ownerKind := kind
+	owners := map[string]bool{name: true}
+	if kind == "Deployment" {
+		var replicaSets appsv1.ReplicaSetList
+		if err := getKubernetesObjects(&replicaSets, "get", "replicaset", "-n", namespace, "-o=json"); err != nil {
+			return nil, err
+		}
+		ownerKind = "ReplicaSet"
+		owners = make(map[string]bool)
+		for _, replicaSet := range replicaSets.Items {
+			if isOwnedBy(replicaSet.OwnerReferences, kind, name) {
+				owners[replicaSet.Name] = true
+			}
+		}
+	}
+
+	ownedPods := make([]k8s.Pod, 0)
+	for _, pod := range pods.Items {
+		for owner := range owners {
+			if isOwnedBy(pod.OwnerReferences, ownerKind, owner) {
+				ownedPods = append(ownedPods, pod)
+				break
+			}
+		}
+	}
+
+	return ownedPods, nil
+}
+
+func isOwnedBy(ownerReferences []metav1.OwnerReference, kind, name string) bool {
+	for _, ownerReference := range ownerReferences {
+		if ownerReference.Kind == kind && ownerReference.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func BaseURL() string {
 
 	url := fmt.Sprintf("http://%s%s", Server, config.BaseURL)
@@ -386,7 +614,7 @@ func TunnelCommand(commandArgs []string) {
 	}
 
 	// Invoke tridentctl inside the Trident pod
-	out, err := exec.Command(KubernetesCLI, execCommand...).CombinedOutput()
+	out, err := exec.Command(KubernetesCLI, kubernetesCLIArgs(execCommand...)...).CombinedOutput()
 
 	SetExitCodeFromError(err)
 	if err != nil {
@@ -413,7 +641,7 @@ func TunnelCommandRaw(commandArgs []string) ([]byte, error) {
 	}
 
 	// Invoke tridentctl inside the Trident pod
-	output, err := exec.Command(KubernetesCLI, execCommand...).CombinedOutput()
+	output, err := exec.Command(KubernetesCLI, kubernetesCLIArgs(execCommand...)...).CombinedOutput()
 
 	SetExitCodeFromError(err)
 	return output, err
@@ -443,12 +671,20 @@ func GetExitCodeFromError(err error) int {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			ws := exitError.Sys().(syscall.WaitStatus)
 			code = ws.ExitStatus()
+		} else if exitCodeErr, ok := err.(*exitCodeError); ok {
+			code = exitCodeErr.code
 		}
 
 		return code
 	}
 }
 
+// exitCodeError is an error that determines the process exit code.
+type exitCodeError struct {
+	error
+	code int
+}
+
 func getUserConfirmation(s string) (bool, error) {
 
 	reader := bufio.NewReader(os.Stdin)
@@ -14,24 +14,40 @@ func Logger(inner http.Handler, routeName string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		requestId := xid.New()
-		logRestCallInfo("REST API call received.", r, start, requestId, routeName)
+		logRestCallInfo("REST API call received.", r, start, requestId, routeName, 0)
 
-		inner.ServeHTTP(w, r)
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		inner.ServeHTTP(recorder, r)
 
 		restOpsTotal.WithLabelValues(r.Method, routeName).Inc()
 		endTime := float64(time.Since(start).Milliseconds())
 		restOpsSecondsTotal.WithLabelValues(r.Method, routeName).Observe(endTime)
 
-		logRestCallInfo("REST API call complete.", r, start, requestId, routeName)
+		logRestCallInfo("REST API call complete.", r, start, requestId, routeName, recorder.status)
 	})
 }
 
-func logRestCallInfo(msg string, r *http.Request, start time.Time, requestId xid.ID, name string) {
-	log.WithFields(log.Fields{
+// statusRecorder wraps a ResponseWriter so the response status code may be logged.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRestCallInfo(msg string, r *http.Request, start time.Time, requestId xid.ID, name string, status int) {
+	fields := log.Fields{
 		"requestID": requestId,
 		"method":    r.Method,
 		"uri":       r.RequestURI,
 		"route":     name,
 		"duration":  time.Since(start),
-	}).Debug(msg)
+	}
+	if status != 0 {
+		fields["status"] = status
+	}
+	log.WithFields(fields).Debug(msg)
 }
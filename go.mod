@@ -27,10 +27,12 @@ require (
 	github.com/mitchellh/copystructure v1.0.0 // *
 	github.com/mitchellh/hashstructure v1.0.0 // *
 	github.com/olekukonko/tablewriter v0.0.4 // +
+	github.com/pmezard/go-difflib v1.0.0 // +
 	github.com/prometheus/client_golang v1.3.0 // +
 	github.com/rs/xid v1.2.1 // *
 	github.com/sirupsen/logrus v1.4.2 // *
 	github.com/spf13/cobra v0.0.5 // *
+	github.com/spf13/pflag v1.0.5 // +
 	github.com/stretchr/testify v1.4.0 // *
 	golang.org/x/crypto v0.0.0-20200109152110-61a87790db17 // github.com/golang/crypto // +
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d // github.com/golang/oauth2 // +